@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/db"
+)
+
+const migrateUsage = "usage: api migrate up|down|status|redo"
+
+// runMigrate handles the "migrate" subcommand
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	database, err := db.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database.DB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "redo":
+		err = migrator.Redo(ctx)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		log.Fatal(migrateUsage)
+	}
+
+	if err != nil {
+		log.Fatalf("Migration %s failed: %v", args[0], err)
+	}
+}
+
+// printMigrationStatus writes a table of migrations to stdout
+func printMigrationStatus(ctx context.Context, migrator *db.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := false
+	for _, s := range statuses {
+		applied = applied || s.Applied
+	}
+	if !applied {
+		fmt.Println("No migrations applied")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Modified {
+			state = "modified"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
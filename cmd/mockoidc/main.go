@@ -0,0 +1,241 @@
+// Command mockoidc is a minimal OpenID Connect provider for trying out and
+// testing single sign-on locally. It signs in every authorization request
+// as the user given by its flags without asking for credentials, so it must
+// never be exposed outside a development machine.
+//
+// Configure the API with, for example:
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9000
+//	OIDC_MOCK_CLIENT_ID=task-manager
+//	OIDC_MOCK_REDIRECT_URL=http://localhost:8080/auth/oidc/mock/callback
+//	OIDC_MOCK_ROLE_CLAIM=groups
+//	OIDC_MOCK_ROLE_MAP=admins=admin
+//
+// A login_hint of the form "sub:email" on the authorization request picks a
+// different user than the flags.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/yourusername/Task_Management/internal/utils"
+)
+
+// authorization is an issued code waiting to be redeemed
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	subject       string
+	email         string
+	expiresAt     time.Time
+}
+
+// provider holds the mock issuer's key and outstanding codes
+type provider struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	name          string
+	emailVerified bool
+	groups        []string
+	subject       string
+	email         string
+	key           *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+func main() {
+	p := &provider{codes: make(map[string]*authorization)}
+	addr := flag.String("addr", ":9000", "listen address")
+	flag.StringVar(&p.issuer, "issuer", "http://localhost:9000", "issuer URL; must match how clients reach this server")
+	flag.StringVar(&p.clientID, "client-id", "task-manager", "accepted client ID")
+	flag.StringVar(&p.clientSecret, "client-secret", "", "client secret; empty accepts public clients")
+	flag.StringVar(&p.subject, "sub", "mock-user-1", "subject of the signed-in user")
+	flag.StringVar(&p.email, "email", "mock.user@example.com", "email of the signed-in user")
+	flag.StringVar(&p.name, "name", "Mock User", "display name of the signed-in user")
+	flag.BoolVar(&p.emailVerified, "email-verified", true, "whether the email is reported as verified")
+	groups := flag.String("groups", "", "comma-separated groups claim")
+	flag.Parse()
+
+	if *groups != "" {
+		p.groups = strings.Split(*groups, ",")
+	}
+	p.issuer = strings.TrimSuffix(p.issuer, "/")
+
+	var err error
+	if p.key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.GET("/.well-known/openid-configuration", p.discovery)
+	router.GET("/jwks", p.jwks)
+	router.GET("/authorize", p.authorize)
+	router.POST("/token", p.token)
+
+	log.Printf("Mock OIDC provider %s listening on %s", p.issuer, *addr)
+	if err := router.Run(*addr); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+func (p *provider) discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.JWKSet{Keys: []utils.JWK{{
+		KeyType:   "RSA",
+		KeyID:     "mock",
+		Use:       "sig",
+		Algorithm: "RS256",
+		N:         base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+// authorize immediately redirects back with a code for the configured user
+func (p *provider) authorize(c *gin.Context) {
+	if c.Query("client_id") != p.clientID {
+		c.String(http.StatusBadRequest, "unknown client_id")
+		return
+	}
+	if c.Query("response_type") != "code" {
+		c.String(http.StatusBadRequest, "response_type must be code")
+		return
+	}
+	if c.Query("code_challenge") == "" || c.Query("code_challenge_method") != "S256" {
+		c.String(http.StatusBadRequest, "PKCE with S256 is required")
+		return
+	}
+	redirectURI, err := url.Parse(c.Query("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		c.String(http.StatusBadRequest, "invalid redirect_uri")
+		return
+	}
+
+	auth := &authorization{
+		clientID:      p.clientID,
+		redirectURI:   c.Query("redirect_uri"),
+		nonce:         c.Query("nonce"),
+		codeChallenge: c.Query("code_challenge"),
+		subject:       p.subject,
+		email:         p.email,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	if sub, email, ok := strings.Cut(c.Query("login_hint"), ":"); ok {
+		auth.subject, auth.email = sub, email
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = auth
+	p.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", c.Query("state"))
+	redirectURI.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, redirectURI.String())
+}
+
+// token redeems a code for an ID token after checking the client and the
+// PKCE verifier
+func (p *provider) token(c *gin.Context) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	if clientID != p.clientID || (p.clientSecret != "" && clientSecret != p.clientSecret) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+	if c.PostForm("grant_type") != "authorization_code" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[c.PostForm("code")]
+	delete(p.codes, c.PostForm("code"))
+	p.mu.Unlock()
+	if !ok || time.Now().After(auth.expiresAt) || auth.redirectURI != c.PostForm("redirect_uri") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(c.PostForm("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            auth.subject,
+		"aud":            auth.clientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": p.emailVerified,
+		"name":           p.name,
+	}
+	if len(p.groups) > 0 {
+		claims["groups"] = p.groups
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// randomString returns a random URL-safe string
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+// Command webhookrecv is a local webhook receiver for trying out and testing
+// webhook delivery. It checks the signature of every delivery and prints
+// it. With -fail it answers every delivery with an error so that retries
+// and dead-lettering can be watched.
+//
+// Webhooks to local addresses are refused unless the API and the worker run
+// with WEBHOOK_ALLOW_PRIVATE_TARGETS=true. Point a webhook at it with, for
+// example:
+//
+//	POST /api/webhooks {"url": "http://localhost:9100/hook", "secret": "<secret>"}
+//
+// and start it with the same secret:
+//
+//	webhookrecv -secret <secret>
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/Task_Management/internal/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":9100", "listen address")
+	secret := flag.String("secret", "", "webhook secret; empty skips signature checks")
+	fail := flag.Int("fail", 0, "answer every delivery with this status instead of 204")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum signature age")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" {
+			if err := webhooks.Verify(*secret, r.Header.Get(webhooks.SignatureHeader), body, *tolerance); err != nil {
+				log.Printf("Rejected delivery %s: %v", r.Header.Get(webhooks.DeliveryHeader), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			pretty.Write(body)
+		}
+		log.Printf("Delivery %s (%s) to %s:\n%s",
+			r.Header.Get(webhooks.DeliveryHeader), r.Header.Get(webhooks.EventHeader), r.URL.Path, pretty.String())
+
+		if *fail != 0 {
+			http.Error(w, "failing on purpose", *fail)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Command worker runs the background work of the application, the job queue,
+// webhook delivery and the periodic schedulers, apart from the API. Start
+// the API with EMBEDDED_WORKER=false when using it. Both load their
+// configuration with config.Load, so give them the same environment, in
+// particular DATABASE_URL and the STORAGE_*, S3_*, MAIL_* and SMTP_*
+// settings. Any number of workers may run at once.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/db"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/mail"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/storage"
+	"github.com/yourusername/Task_Management/internal/webhooks"
+	"github.com/yourusername/Task_Management/internal/worker"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	database, err := db.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// Stop on SIGINT or SIGTERM, letting running jobs finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Events published here reach the clients of every API instance through
+	// Postgres. The worker only sends events, so the transport is not run.
+	broker := events.NewBroker(1)
+	broker.SetTransport(events.NewPGTransport(database.DB, cfg.DatabaseURL, broker, time.Hour))
+
+	runner := worker.NewRunner(cfg, database.DB, store, mailer, broker)
+	broker.AddSink(webhooks.NewOutbox(models.NewWebhookRepository(database.DB), runner.Webhooks()))
+
+	log.Printf("Starting worker")
+	runner.Run(ctx)
+	log.Printf("Worker stopped")
+}
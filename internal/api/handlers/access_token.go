@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// AccessTokenHandler lets users manage their personal access tokens
+type AccessTokenHandler struct {
+	accessTokenRepo *models.AccessTokenRepository
+	validate        *validator.Validate
+}
+
+// NewAccessTokenHandler creates a new personal access token handler
+func NewAccessTokenHandler(accessTokenRepo *models.AccessTokenRepository) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenRepo: accessTokenRepo,
+		validate:        validator.New(),
+	}
+}
+
+// createAccessTokenRequest is the body of POST /api/account/tokens. Tokens
+// without ExpiresAt never expire.
+type createAccessTokenRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetTokens lists the requesting user's active personal access tokens
+func (h *AccessTokenHandler) GetTokens(c *gin.Context) {
+	tokens, err := h.accessTokenRepo.ListByUser(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken issues a personal access token. The token itself is only in
+// this response.
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	var req createAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !rbac.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	token := &models.PersonalAccessToken{
+		UserID: currentUser(c),
+		Name:   req.Name,
+		Scopes: uniqueStrings(req.Scopes),
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	plaintext, err := h.accessTokenRepo.Create(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":                 plaintext,
+		"personal_access_token": token,
+		"message":               "Copy the token now; it cannot be shown again",
+	})
+}
+
+// RevokeToken stops one of the requesting user's tokens from working
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("tokenID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := h.accessTokenRepo.Revoke(id, currentUser(c)); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
+
+// uniqueStrings returns values without duplicates, in their original order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/mail"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+const (
+	// verifyEmailTTL is how long an email verification link works
+	verifyEmailTTL = 48 * time.Hour
+	// resetPasswordTTL is how long a password reset link works
+	resetPasswordTTL = time.Hour
+)
+
+// emailRequest is the body of requests that start an emailed flow
+type emailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// tokenRequest is the body of POST /verify-email
+type tokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// resetPasswordRequest is the body of POST /password-reset/confirm
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// blockedUnverified rejects users whose email is unverified when the policy
+// blocks them from signing in. It writes an error response and returns true
+// if the user is blocked.
+func (h *AuthHandler) blockedUnverified(c *gin.Context, user *models.User) bool {
+	if user.EmailVerifiedAt != nil || h.config.UnverifiedPolicy != config.UnverifiedBlock {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Email address must be verified"})
+	return true
+}
+
+// blockedDeactivated rejects users whose account is deactivated. It writes
+// an error response and returns true if the user is deactivated.
+func (h *AuthHandler) blockedDeactivated(c *gin.Context, user *models.User) bool {
+	if user.DeactivatedAt == nil {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+	return true
+}
+
+// appLink builds a link into the web app carrying token
+func (h *AuthHandler) appLink(path, token string) string {
+	return h.config.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerification mails a verification link to user. Failures are logged
+// rather than reported so that they do not reveal anything to the client.
+func (h *AuthHandler) sendVerification(c *gin.Context, user *models.User) {
+	token, err := h.userTokenRepo.Create(user.ID, models.TokenVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to create verification token")
+		return
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\nThe link expires in %d hours.\n",
+			user.Username, h.appLink("/verify-email", token), int(verifyEmailTTL.Hours()),
+		),
+	}
+	if err := h.mailer.Send(c.Request.Context(), msg); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send verification email")
+	}
+}
+
+// bindEmail reads an email request. It writes an error response and returns
+// false on failure.
+func (h *AuthHandler) bindEmail(c *gin.Context) (string, bool) {
+	var req emailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return "", false
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return "", false
+	}
+
+	return req.Email, true
+}
+
+// VerifyEmail confirms a user's email address with a mailed token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	userID, err := h.userTokenRepo.Consume(req.Token, models.TokenVerifyEmail)
+	if err == models.ErrInvalidUserToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	if err := h.userRepo.MarkEmailVerified(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification mails a new verification link. The response is the
+// same whether or not the address belongs to an unverified account.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	email, ok := h.bindEmail(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.FindByEmail(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if user != nil && user.EmailVerifiedAt == nil {
+		h.sendVerification(c, user)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address belongs to an unverified account, a new link has been sent"})
+}
+
+// RequestPasswordReset mails a password reset link. The response is the same
+// whether or not the address is registered.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	email, ok := h.bindEmail(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.FindByEmail(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if user != nil {
+		token, err := h.userTokenRepo.Create(user.ID, models.TokenResetPassword, resetPasswordTTL)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to create password reset token")
+		} else {
+			msg := mail.Message{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body: fmt.Sprintf(
+					"Hi %s,\n\nSomeone asked to reset your password. If it was you, open this link to choose a new one:\n\n%s\n\n"+
+						"The link expires in %d minutes. If you did not ask for this, you can ignore this email.\n",
+					user.Username, h.appLink("/reset-password", token), int(resetPasswordTTL.Minutes()),
+				),
+			}
+			if err := h.mailer.Send(c.Request.Context(), msg); err != nil {
+				logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send password reset email")
+			}
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a mailed token and ends all of the
+// user's sessions
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	userID, err := h.userTokenRepo.Consume(req.Token, models.TokenResetPassword)
+	if err == models.ErrInvalidUserToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := h.userRepo.SetPassword(userID, req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// The reset link proves control of the mailbox
+	if err := h.userRepo.MarkEmailVerified(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := h.tokenRepo.RevokeAllRefreshTokens(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end existing sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// assigneesRequest is the body of PUT /api/tasks/:id/assignees
+type assigneesRequest struct {
+	UserIDs []int `json:"user_ids" validate:"required"`
+}
+
+// GetAssignees returns the users assigned to a task
+func (h *TaskHandler) GetAssignees(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	assignees, err := h.assignRepo.Assignees(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignees"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignees)
+}
+
+// SetAssignees replaces the assignees of a task. Only the task's creator,
+// one of its current assignees or a user with task:reassign:any can
+// reassign it.
+func (h *TaskHandler) SetAssignees(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	var req assigneesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	userID := currentUser(c)
+	if !can(c, rbac.TaskReassignAny) && (task.CreatedBy == nil || *task.CreatedBy != userID) {
+		assigned, err := h.assignRepo.IsAssignee(task.ID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !assigned {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator or an assignee can reassign this task"})
+			return
+		}
+	}
+
+	if !h.saveAssignees(c, task, req.UserIDs) {
+		return
+	}
+
+	assignees, err := h.assignRepo.Assignees(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignees"})
+		return
+	}
+
+	task.Assignees = make([]int, len(assignees))
+	for i, a := range assignees {
+		task.Assignees[i] = a.UserID
+	}
+	publishTaskEvent(c, h.broker, h.projectRepo, events.TaskUpdated, task, task)
+
+	c.JSON(http.StatusOK, assignees)
+}
+
+// saveAssignees replaces a task's assignees on behalf of the requesting user.
+// It writes an error response and returns false on failure.
+func (h *TaskHandler) saveAssignees(c *gin.Context, task *models.Task, userIDs []int) bool {
+	actorID := currentUser(c)
+
+	err := h.assignRepo.SetAssignees(task, userIDs, actorID)
+	if err == models.ErrInvalidAssignee {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save assignees"})
+		return false
+	}
+
+	// Reminders go to the assignees
+	scheduleReminders(h.reminderRepo, task.ID)
+	return true
+}
+
+// GetAssignmentHistory returns who was assigned to and unassigned from a task
+func (h *TaskHandler) GetAssignmentHistory(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	history, err := h.assignRepo.History(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// WatchTask subscribes the authenticated user to a task
+func (h *TaskHandler) WatchTask(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	userID := currentUser(c)
+	if err := h.assignRepo.Watch(task.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to watch task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watching task"})
+}
+
+// UnwatchTask unsubscribes the authenticated user from a task
+func (h *TaskHandler) UnwatchTask(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	userID := currentUser(c)
+	if err := h.assignRepo.Unwatch(task.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unwatch task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "No longer watching task"})
+}
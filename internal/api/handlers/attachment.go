@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/storage"
+)
+
+// multipartOverhead is the room allowed for multipart framing around the file
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler handles file uploads on tasks
+type AttachmentHandler struct {
+	attachRepo   *models.AttachmentRepository
+	taskRepo     *models.TaskRepository
+	projectRepo  *models.ProjectRepository
+	store        storage.Storage
+	maxBytes     int64
+	allowedTypes []string
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(attachRepo *models.AttachmentRepository, taskRepo *models.TaskRepository, projectRepo *models.ProjectRepository, store storage.Storage, cfg *config.Config) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachRepo:   attachRepo,
+		taskRepo:     taskRepo,
+		projectRepo:  projectRepo,
+		store:        store,
+		maxBytes:     cfg.Attachments.MaxBytes,
+		allowedTypes: cfg.Attachments.AllowedTypes,
+	}
+}
+
+// loadTask finds the task named by :id and checks that the requesting user
+// has at least level access to it. It writes an error response and returns
+// false on failure.
+func (h *AttachmentHandler) loadTask(c *gin.Context, level models.AccessLevel) (*models.Task, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return nil, false
+	}
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	}
+
+	if !authorizeTask(c, h.projectRepo, task, level) {
+		return nil, false
+	}
+
+	return task, true
+}
+
+// loadAttachment finds the attachment named by :attachmentID on task. It
+// writes an error response and returns false on failure.
+func (h *AttachmentHandler) loadAttachment(c *gin.Context, task *models.Task) (*models.Attachment, bool) {
+	id, err := strconv.Atoi(c.Param("attachmentID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return nil, false
+	}
+
+	attachment, err := h.attachRepo.FindByID(id)
+	if err != nil || attachment.TaskID != task.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return nil, false
+	}
+
+	return attachment, true
+}
+
+// isAllowedType reports whether contentType matches the configured allow list
+func (h *AttachmentHandler) isAllowedType(contentType string) bool {
+	for _, allowed := range h.allowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffContentType detects the MIME type of file from its first bytes
+func sniffContentType(file multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	return mediaType, nil
+}
+
+// hashFile returns the hex SHA-256 of file and rewinds it
+func hashFile(file multipart.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GetAttachments lists the attachments of a task
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	task, ok := h.loadTask(c, models.AccessRead)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachRepo.ListByTask(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// UploadAttachment stores the multipart "file" field as a task attachment.
+// Identical contents are stored only once.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	task, ok := h.loadTask(c, models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A multipart \"file\" field within the size limit is required"})
+		return
+	}
+	if header.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d byte limit", h.maxBytes)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	// The type is taken from the contents, not from what the client claims
+	contentType, err := sniffContentType(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	if !h.isAllowedType(contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type not allowed", "content_type": contentType, "allowed": h.allowedTypes})
+		return
+	}
+
+	hash, err := hashFile(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+
+	exists, err := h.attachRepo.BlobExists(hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+	if !exists && !h.putBlob(c, hash, file, header.Size, contentType) {
+		return
+	}
+
+	userID := currentUser(c)
+	attachment := models.Attachment{
+		TaskID:      task.ID,
+		Filename:    filepath.Base(header.Filename),
+		SHA256:      hash,
+		Size:        header.Size,
+		ContentType: contentType,
+		UploadedBy:  &userID,
+	}
+	inserted, err := h.attachRepo.Create(&attachment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+
+	// The blob was swept between the existence check and now; store it again
+	if exists && inserted {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+			return
+		}
+		if !h.putBlob(c, hash, file, header.Size, contentType) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// putBlob stores file contents under their hash. It writes an error response
+// and returns false on failure.
+func (h *AttachmentHandler) putBlob(c *gin.Context, hash string, file io.Reader, size int64, contentType string) bool {
+	if err := h.store.Put(c.Request.Context(), models.BlobKey(hash), file, size, contentType); err != nil {
+		logrus.WithError(err).Error("Failed to store attachment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return false
+	}
+	return true
+}
+
+// DownloadAttachment streams an attachment's contents
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	task, ok := h.loadTask(c, models.AccessRead)
+	if !ok {
+		return
+	}
+
+	attachment, ok := h.loadAttachment(c, task)
+	if !ok {
+		return
+	}
+
+	etag := `"` + attachment.SHA256 + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	contents, err := h.store.Get(c.Request.Context(), attachment.StorageKey())
+	if err == storage.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment contents are missing"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read attachment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+	defer contents.Close()
+
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, contents, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+	})
+}
+
+// DeleteAttachment removes an attachment from a task. Contents shared with
+// other attachments are kept; orphaned contents are swept in the background.
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	task, ok := h.loadTask(c, models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	attachment, ok := h.loadAttachment(c, task)
+	if !ok {
+		return
+	}
+
+	if err := h.attachRepo.Delete(attachment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
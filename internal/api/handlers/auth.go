@@ -1,30 +1,104 @@
 package handlers
 
 import (
-	
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/mail"
 	"github.com/yourusername/Task_Management/internal/models"
 	"github.com/yourusername/Task_Management/internal/utils"
 )
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	userRepo *models.UserRepository
-	config   *config.Config
-	validate *validator.Validate
+	userRepo      *models.UserRepository
+	tokenRepo     *models.TokenRepository
+	userTokenRepo *models.UserTokenRepository
+	mfaRepo       *models.MFARepository
+	throttleRepo  *models.LoginThrottleRepository
+	auditRepo     *models.AuditRepository
+	keys          *utils.KeySet
+	mailer        mail.Mailer
+	config        *config.Config
+	validate      *validator.Validate
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(userRepo *models.UserRepository, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(userRepo *models.UserRepository, tokenRepo *models.TokenRepository, userTokenRepo *models.UserTokenRepository, mfaRepo *models.MFARepository, throttleRepo *models.LoginThrottleRepository, auditRepo *models.AuditRepository, keys *utils.KeySet, mailer mail.Mailer, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
-		userRepo: userRepo,
-		config:   cfg,
-		validate: validator.New(),
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		userTokenRepo: userTokenRepo,
+		mfaRepo:       mfaRepo,
+		throttleRepo:  throttleRepo,
+		auditRepo:     auditRepo,
+		keys:          keys,
+		mailer:        mailer,
+		config:        cfg,
+		validate:      validator.New(),
+	}
+}
+
+// refreshRequest is the body of POST /refresh
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// mfaLoginRequest is the body of POST /login/mfa. Code is either a TOTP code
+// or a recovery code.
+type mfaLoginRequest struct {
+	Challenge string `json:"challenge" validate:"required"`
+	Code      string `json:"code" validate:"required"`
+}
+
+// logoutRequest is the body of POST /logout. With All set every session of
+// the user is ended; otherwise the session of RefreshToken is.
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+// issueTokens creates an access token and returns it with refreshToken.
+// It writes an error response and returns nil on failure.
+func (h *AuthHandler) issueTokens(c *gin.Context, user *models.User, refreshToken string) gin.H {
+	mfaPending, err := h.mfaRepo.EnrollmentRequired(user.ID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return nil
+	}
+	
+	token, err := utils.GenerateToken(user, h.keys, h.config.TokenExpiration, mfaPending)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return nil
+	}
+	
+	session := gin.H{
+		"user":          user,
+		"token":         token,
+		"expires_in":    int(h.config.TokenExpiration.Seconds()),
+		"refresh_token": refreshToken,
+	}
+	if mfaPending {
+		session["mfa_enrollment_required"] = true
 	}
+	return session
+}
+
+// startSession issues an access token and a refresh token in a new token
+// family. It writes an error response and returns nil on failure.
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User) gin.H {
+	refreshToken, err := h.tokenRepo.CreateRefreshToken(user.ID, h.config.RefreshTokenExpiration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return nil
+	}
+	
+	return h.issueTokens(c, user, refreshToken)
 }
 
 // Register handles user registration
@@ -72,17 +146,24 @@ if err != nil {
 		return
 	}
 	
-	// Generate JWT token
-	token, err := utils.GenerateToken(user, h.config.JWTSecret, h.config.TokenExpiration)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	h.sendVerification(c, user)
+	
+	// Under the block policy the account is unusable until verified
+	if h.config.UnverifiedPolicy == config.UnverifiedBlock {
+		c.JSON(http.StatusCreated, gin.H{
+			"user":    user,
+			"message": "Check your email to verify your address before signing in",
+		})
+		return
+	}
+	
+	// Generate access and refresh tokens
+	session := h.startSession(c, user)
+	if session == nil {
 		return
 	}
 	
-	c.JSON(http.StatusCreated, gin.H{
-		"user":  user,
-		"token": token,
-	})
+	c.JSON(http.StatusCreated, session)
 }
 
 // Login handles user authentication
@@ -93,28 +174,221 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 	
+	keys := throttleKeys(c, req.Username)
+	if !h.loginAllowed(c, keys) {
+		return
+	}
+	
 	// Find user by username
 	user, err := h.userRepo.FindByUsername(req.Username)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
 	
-	// Verify password
+	// Verify password; unknown users take as long and get the same response
 	if !h.userRepo.CheckPassword(user, req.Password) {
+		h.recordLoginFailure(c, keys, req.Username, user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 	
-	// Generate JWT token
-	token, err := utils.GenerateToken(user, h.config.JWTSecret, h.config.TokenExpiration)
+	// Failures from the client IP still count towards its limit
+	if _, err := h.throttleRepo.Reset(keys[0]); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	
+	h.completeLogin(c, user)
+}
+
+// completeLogin finishes signing in a user whose first factor checked out.
+// Users with MFA get a challenge to finish signing in at /login/mfa; others
+// get tokens.
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	if h.blockedDeactivated(c, user) || h.blockedUnverified(c, user) {
+		return
+	}
+	
+	mfaEnabled, err := h.mfaRepo.IsEnabled(user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if mfaEnabled {
+		challenge, err := h.mfaRepo.CreateChallenge(user.ID, mfaChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA challenge"})
+			return
+		}
+		
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"challenge":    challenge,
+			"expires_in":   int(mfaChallengeTTL.Seconds()),
+		})
+		return
+	}
+	
+	// Generate access and refresh tokens
+	session := h.startSession(c, user)
+	if session == nil {
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"user":  user,
-		"token": token,
-	})
+	c.JSON(http.StatusOK, session)
+}
+
+// LoginMFA completes a login with the challenge returned by Login and a TOTP
+// or recovery code
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	
+	userID, err := h.mfaRepo.AttemptChallenge(req.Challenge)
+	if err == models.ErrInvalidChallenge {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	
+	enrollment, err := h.mfaRepo.Enrollment(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	ok, err := matchMFACode(h.mfaRepo, enrollment, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+	
+	if err := h.mfaRepo.CompleteChallenge(req.Challenge); err == models.ErrInvalidChallenge {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if h.blockedDeactivated(c, user) {
+		return
+	}
+	
+	session := h.startSession(c, user)
+	if session == nil {
+		return
+	}
+	
+	c.JSON(http.StatusOK, session)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token can be used once.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	
+	refreshToken, userID, err := h.tokenRepo.RotateRefreshToken(req.RefreshToken, h.config.RefreshTokenExpiration)
+	if err == models.ErrInvalidRefreshToken || err == models.ErrRefreshTokenReused {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	
+	// Roles may have changed since the session started
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrInvalidRefreshToken.Error()})
+		return
+	}
+	if h.blockedDeactivated(c, user) || h.blockedUnverified(c, user) {
+		return
+	}
+	
+	session := h.issueTokens(c, user, refreshToken)
+	if session == nil {
+		return
+	}
+	
+	c.JSON(http.StatusOK, session)
+}
+
+// Logout revokes the current access token and ends either the session of
+// the given refresh token or, with "all", every session of the user. Other
+// access tokens already issued stay valid until they expire.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req logoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	userID, _ := c.Get("userID")
+	
+	switch {
+	case req.All:
+		if err := h.tokenRepo.RevokeAllRefreshTokens(userID.(int)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	case req.RefreshToken != "":
+		err := h.tokenRepo.RevokeRefreshToken(req.RefreshToken, userID.(int))
+		if err != nil && err != models.ErrInvalidRefreshToken {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+	
+	tokenID, _ := c.Get("tokenID")
+	expiresAt, _ := c.Get("tokenExpiresAt")
+	if jti := tokenID.(string); jti != "" {
+		if err := h.tokenRepo.RevokeAccessToken(jti, expiresAt.(time.Time)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// JWKS publishes the public keys that verify access tokens
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
 }
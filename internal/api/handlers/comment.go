@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// CommentHandler handles discussion threads on tasks
+type CommentHandler struct {
+	commentRepo *models.CommentRepository
+	taskRepo    *models.TaskRepository
+	userRepo    *models.UserRepository
+	projectRepo *models.ProjectRepository
+	broker      *events.Broker
+	validate    *validator.Validate
+}
+
+// NewCommentHandler creates a new comment handler. Changes to comments are
+// published to broker.
+func NewCommentHandler(commentRepo *models.CommentRepository, taskRepo *models.TaskRepository, userRepo *models.UserRepository, projectRepo *models.ProjectRepository, broker *events.Broker) *CommentHandler {
+	return &CommentHandler{
+		commentRepo: commentRepo,
+		taskRepo:    taskRepo,
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		broker:      broker,
+		validate:    validator.New(),
+	}
+}
+
+// commentRequest is the body of comment create and edit requests
+type commentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// loadTask finds the task named by :id and checks that the requesting user
+// may see it. It writes an error response and returns false on failure.
+func (h *CommentHandler) loadTask(c *gin.Context) (*models.Task, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return nil, false
+	}
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	}
+
+	if !authorizeTask(c, h.projectRepo, task, models.AccessRead) {
+		return nil, false
+	}
+
+	return task, true
+}
+
+// loadComment finds the live comment named by :commentID on task. It writes
+// an error response and returns false on failure.
+func (h *CommentHandler) loadComment(c *gin.Context, task *models.Task) (*models.Comment, bool) {
+	id, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return nil, false
+	}
+
+	comment, err := h.commentRepo.FindByID(id)
+	if err != nil || comment.TaskID != task.ID || comment.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+
+	return comment, true
+}
+
+// bindComment reads and validates a comment body and resolves its mentions.
+// It writes an error response and returns false on failure.
+func (h *CommentHandler) bindComment(c *gin.Context, comment *models.Comment) bool {
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return false
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return false
+	}
+
+	// Mentions of unknown usernames are left as plain text
+	comment.Body = req.Body
+	comment.Mentions = nil
+	for _, username := range models.ParseMentions(req.Body) {
+		user, err := h.userRepo.FindByUsername(username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+			return false
+		}
+		if user != nil {
+			comment.Mentions = append(comment.Mentions, user.ID)
+		}
+	}
+
+	return true
+}
+
+// GetComments returns the comment thread of a task
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.commentRepo.ListByTask(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment adds a comment to a task
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	userID := currentUser(c)
+	comment := models.Comment{TaskID: task.ID, UserID: &userID}
+	if !h.bindComment(c, &comment) {
+		return
+	}
+
+	if err := h.commentRepo.Create(&comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+	publishTaskEvent(c, h.broker, h.projectRepo, events.CommentCreated, task, comment)
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment edits a comment; only its author can do this
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.loadComment(c, task)
+	if !ok {
+		return
+	}
+
+	userID := currentUser(c)
+	if comment.UserID == nil || *comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit a comment"})
+		return
+	}
+
+	if !h.bindComment(c, comment) {
+		return
+	}
+
+	if err := h.commentRepo.Update(comment, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+	publishTaskEvent(c, h.broker, h.projectRepo, events.CommentUpdated, task, comment)
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment soft-deletes a comment. The author can delete their own
+// comments; users who manage the task can delete any of them.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.loadComment(c, task)
+	if !ok {
+		return
+	}
+
+	userID := currentUser(c)
+	if comment.UserID == nil || *comment.UserID != userID {
+		if !authorizeTask(c, h.projectRepo, task, models.AccessManage) {
+			return
+		}
+	}
+
+	if err := h.commentRepo.Delete(comment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	publishTaskEvent(c, h.broker, h.projectRepo, events.CommentDeleted, task, gin.H{"id": comment.ID, "task_id": task.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// GetCommentHistory returns the earlier versions of a comment
+func (h *CommentHandler) GetCommentHistory(c *gin.Context) {
+	task, ok := h.loadTask(c)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.loadComment(c, task)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.commentRepo.Revisions(comment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetMentions returns comments that mention the authenticated user
+func (h *CommentHandler) GetMentions(c *gin.Context) {
+	userID := currentUser(c)
+
+	comments, err := h.commentRepo.ListMentioning(userID, can(c, rbac.TaskReadAny))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mentions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// addDependencyRequest is the body of POST /api/tasks/:id/dependencies
+type addDependencyRequest struct {
+	DependsOnID int `json:"depends_on_id"`
+}
+
+// loadTask finds the task named by the given path parameter and checks that
+// the requesting user has at least level access to it. It writes an error
+// response and returns false on failure.
+func (h *TaskHandler) loadTask(c *gin.Context, param string, level models.AccessLevel) (*models.Task, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return nil, false
+	}
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	}
+
+	if !authorizeTask(c, h.projectRepo, task, level) {
+		return nil, false
+	}
+
+	return task, true
+}
+
+// checkParent verifies that parentID is a task the user can edit and that
+// nesting taskID under it keeps the hierarchy acyclic. taskID is 0 for new
+// tasks. It writes an error response and returns false on failure.
+func (h *TaskHandler) checkParent(c *gin.Context, taskID, parentID int) bool {
+	parent, err := h.taskRepo.FindByID(parentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent task not found"})
+		return false
+	}
+
+	access, err := h.projectRepo.TaskAccess(parent, currentUser(c), grants(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if access < models.AccessRead {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent task not found"})
+		return false
+	}
+	if access < models.AccessWrite {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add subtasks to the parent task"})
+		return false
+	}
+
+	if taskID == 0 {
+		return true
+	}
+
+	err = h.depRepo.CheckParent(taskID, parentID)
+	if err == models.ErrHierarchyCycle {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check task hierarchy"})
+		return false
+	}
+
+	return true
+}
+
+// GetChildren returns the subtasks of a task that the user can see
+func (h *TaskHandler) GetChildren(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	children, err := h.depRepo.Children(task.ID, visibleTasks(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subtasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, children)
+}
+
+// GetDependencies returns the tasks blocking a task and the tasks it blocks,
+// leaving out those the user cannot see
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessRead)
+	if !ok {
+		return
+	}
+
+	blockedBy, err := h.depRepo.Blockers(task.ID, visibleTasks(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dependencies"})
+		return
+	}
+
+	blocks, err := h.depRepo.Dependents(task.ID, visibleTasks(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dependencies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blocked_by": blockedBy,
+		"blocks":     blocks,
+	})
+}
+
+// AddDependency marks a task as blocked by another task
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	var req addDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.DependsOnID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// The blocking task must be visible to the user as well
+	blocker, err := h.taskRepo.FindByID(req.DependsOnID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blocking task not found"})
+		return
+	}
+	actorID := currentUser(c)
+	access, err := h.projectRepo.TaskAccess(blocker, actorID, grants(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if access < models.AccessRead {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blocking task not found"})
+		return
+	}
+
+	dep := models.Dependency{TaskID: task.ID, DependsOnID: blocker.ID, CreatedBy: &actorID}
+	err = h.depRepo.Add(&dep)
+	if err == models.ErrDependencyCycle {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add dependency"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dep)
+}
+
+// RemoveDependency removes a blocking relationship
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	task, ok := h.loadTask(c, "id", models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	dependsOnID, err := strconv.Atoi(c.Param("dependsOnID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	if err := h.depRepo.Remove(task.ID, dependsOnID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove dependency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency removed successfully"})
+}
+
+// GetNextTasks returns the authenticated user's open tasks in an order that
+// respects dependencies, plus the subset that can be started right now
+func (h *TaskHandler) GetNextTasks(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	ready, queue, err := h.depRepo.WorkQueue(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ready": ready,
+		"queue": queue,
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+	"golang.org/x/net/websocket"
+)
+
+// eventHeartbeat is how often idle streams send something so that proxies
+// keep them open
+const eventHeartbeat = 25 * time.Second
+
+// eventWriteTimeout bounds how long a WebSocket write may block
+const eventWriteTimeout = 10 * time.Second
+
+// EventHandler streams task and comment events to the users who may see the
+// tasks
+type EventHandler struct {
+	broker *events.Broker
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(broker *events.Broker) *EventHandler {
+	return &EventHandler{broker: broker}
+}
+
+// visibleTo returns a filter for the events the requesting user may see. It
+// follows TaskAccess, with the user's permissions at the time the stream was
+// opened and the memberships and assignments carried by each event.
+func (h *EventHandler) visibleTo(c *gin.Context) func(*events.Event) bool {
+	userID, g := currentUser(c), grants(c)
+	return func(event *events.Event) bool {
+		if g.AnyTask >= models.AccessRead {
+			return true
+		}
+		if event.ProjectID != nil && (g.AnyProject >= models.AccessRead || containsID(event.MemberIDs, userID)) {
+			return true
+		}
+		if g.OwnTask < models.AccessRead {
+			return false
+		}
+		return (event.ProjectID == nil && event.OwnerID == userID) || containsID(event.AssigneeIDs, userID)
+	}
+}
+
+// containsID reports whether ids contains id
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// streamDeadline returns a channel that fires when the credentials the
+// stream was opened with expire; clients then reconnect with fresh ones
+func streamDeadline(c *gin.Context) <-chan time.Time {
+	value, ok := c.Get("tokenExpiresAt")
+	if !ok {
+		return nil
+	}
+	return time.After(time.Until(value.(time.Time)))
+}
+
+// lastEventID reads the ID a client resumes from: the Last-Event-ID header
+// EventSource sends on reconnect, or the last_event_id query parameter
+func lastEventID(c *gin.Context) int64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	id, _ := strconv.ParseInt(value, 10, 64)
+	return id
+}
+
+// Stream sends events as server-sent events. Clients resume with
+// Last-Event-ID; if events were missed beyond the replay buffer a "reset"
+// event tells them to reload instead.
+func (h *EventHandler) Stream(c *gin.Context) {
+	visible := h.visibleTo(c)
+	deadline := streamDeadline(c)
+
+	sub, backlog, complete := h.broker.Subscribe(lastEventID(c))
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range backlog {
+		if visible(event) {
+			writeSSE(w, event)
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline:
+			return
+		case event, ok := <-sub.C:
+			// A closed channel means this stream fell behind; the client
+			// resumes from the replay buffer when it reconnects
+			if !ok {
+				return
+			}
+			if !visible(event) {
+				continue
+			}
+			writeSSE(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		w.Flush()
+	}
+}
+
+// writeSSE writes event as a server-sent event
+func writeSSE(w gin.ResponseWriter, event *events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode event")
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
+// wsMessage is a control message sent over the WebSocket alongside events
+type wsMessage struct {
+	Type string `json:"type"`
+}
+
+// WebSocket sends events as JSON messages over a WebSocket. Clients resume
+// with the last_event_id query parameter; a {"type":"reset"} message tells
+// them events were missed.
+func (h *EventHandler) WebSocket(c *gin.Context) {
+	visible := h.visibleTo(c)
+	deadline := streamDeadline(c)
+	lastID := lastEventID(c)
+
+	server := websocket.Server{
+		// Connections are authenticated with bearer tokens rather than
+		// cookies, so any origin may connect
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			h.serveWebSocket(ws, lastID, visible, deadline)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveWebSocket sends events over an open WebSocket until either side
+// closes it
+func (h *EventHandler) serveWebSocket(ws *websocket.Conn, lastID int64, visible func(*events.Event) bool, deadline <-chan time.Time) {
+	defer ws.Close()
+
+	sub, backlog, complete := h.broker.Subscribe(lastID)
+	defer sub.Close()
+
+	// Clients have nothing to say; reading only notices when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var msg string
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	send := func(v interface{}) bool {
+		ws.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		return websocket.JSON.Send(ws, v) == nil
+	}
+
+	if !complete && !send(wsMessage{Type: "reset"}) {
+		return
+	}
+	for _, event := range backlog {
+		if visible(event) && !send(event) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-deadline:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if visible(event) && !send(event) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(wsMessage{Type: "ping"}) {
+				return
+			}
+		}
+	}
+}
+
+// publishTaskEvent announces a change to task, or to one of its comments,
+// with data as the payload. Failures are logged; the change itself is
+// already saved.
+func publishTaskEvent(c *gin.Context, broker *events.Broker, projectRepo *models.ProjectRepository, eventType string, task *models.Task, data interface{}) {
+	if broker == nil {
+		return
+	}
+
+	audience, err := projectRepo.TaskAudience(task)
+	if err != nil {
+		logrus.WithError(err).WithField("type", eventType).Error("Failed to resolve event audience")
+		return
+	}
+	publishEvent(c, broker, eventType, task, audience, data)
+}
+
+// publishEvent is publishTaskEvent with the audience already resolved, for
+// changes such as deletions after which it can no longer be
+func publishEvent(c *gin.Context, broker *events.Broker, eventType string, task *models.Task, audience *models.TaskAudience, data interface{}) {
+	if broker == nil {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logrus.WithError(err).WithField("type", eventType).Error("Failed to encode event")
+		return
+	}
+
+	event := &events.Event{
+		Type:        eventType,
+		TaskID:      task.ID,
+		ProjectID:   task.ProjectID,
+		OwnerID:     task.UserID,
+		ActorID:     currentUser(c),
+		MemberIDs:   audience.MemberIDs,
+		AssigneeIDs: audience.AssigneeIDs,
+		Data:        payload,
+	}
+	// The change is saved even if the client has gone away meanwhile
+	ctx := context.WithoutCancel(c.Request.Context())
+	if err := broker.Publish(ctx, event); err != nil {
+		logrus.WithError(err).WithField("type", eventType).Error("Failed to publish event")
+	}
+}
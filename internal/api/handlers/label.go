@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// LabelHandler handles label-related requests
+type LabelHandler struct {
+	labelRepo *models.LabelRepository
+	validate  *validator.Validate
+}
+
+// NewLabelHandler creates a new label handler
+func NewLabelHandler(labelRepo *models.LabelRepository) *LabelHandler {
+	return &LabelHandler{
+		labelRepo: labelRepo,
+		validate:  validator.New(),
+	}
+}
+
+// createLabelRequest is the body of POST /api/labels
+type createLabelRequest struct {
+	Name   string  `json:"name" validate:"required,min=1,max=50"`
+	Color  *string `json:"color" validate:"omitempty,hexcolor"`
+	Global bool    `json:"global"`
+}
+
+// GetLabels returns global labels and the authenticated user's own labels
+func (h *LabelHandler) GetLabels(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	labels, err := h.labelRepo.ListVisible(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch labels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// CreateLabel creates a personal label, or a global one with label:manage
+func (h *LabelHandler) CreateLabel(c *gin.Context) {
+	var req createLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	label := models.Label{Name: req.Name, Color: req.Color}
+	if req.Global {
+		// Only users with label:manage can create labels shared by everyone
+		if !can(c, rbac.LabelManage) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+	} else {
+		uid := currentUser(c)
+		label.UserID = &uid
+	}
+
+	err := h.labelRepo.Create(&label)
+	if err == models.ErrLabelExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// DeleteLabel removes a label owned by the user, or any label with label:manage
+func (h *LabelHandler) DeleteLabel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	label, err := h.labelRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	// Only the label owner or a user with label:manage can delete the label
+	isOwner := label.UserID != nil && *label.UserID == currentUser(c)
+	if !isOwner && !can(c, rbac.LabelManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	if err := h.labelRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
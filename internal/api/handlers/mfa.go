@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/utils"
+)
+
+// mfaChallengeTTL is how long the second login step may take
+const mfaChallengeTTL = 5 * time.Minute
+
+// MFAHandler handles TOTP enrollment and the MFA policy
+type MFAHandler struct {
+	mfaRepo  *models.MFARepository
+	userRepo *models.UserRepository
+	issuer   string
+	validate *validator.Validate
+}
+
+// NewMFAHandler creates a new MFA handler. issuer is the name shown in
+// authenticator apps.
+func NewMFAHandler(mfaRepo *models.MFARepository, userRepo *models.UserRepository, issuer string) *MFAHandler {
+	return &MFAHandler{
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		issuer:   issuer,
+		validate: validator.New(),
+	}
+}
+
+// mfaCodeRequest is the body of requests that must be confirmed with a code
+type mfaCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// mfaPolicyRequest is the body of PUT /api/mfa/policy
+type mfaPolicyRequest struct {
+	RequiredRoles []string `json:"required_roles" validate:"dive,required,max=20"`
+}
+
+// matchMFACode checks a TOTP code, or a recovery code once MFA is enabled,
+// against a user's enrollment. Accepted codes cannot be used again.
+func matchMFACode(mfaRepo *models.MFARepository, enrollment *models.MFAEnrollment, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := utils.ValidateTOTP(enrollment.Secret, code, time.Now()); ok {
+		return mfaRepo.AcceptStep(enrollment.UserID, step)
+	}
+
+	if enrollment.EnabledAt == nil {
+		return false, nil
+	}
+	return mfaRepo.UseRecoveryCode(enrollment.UserID, code)
+}
+
+// bindCode reads a code request and checks it against the requesting user's
+// enabled MFA. It writes an error response and returns false on failure.
+func (h *MFAHandler) bindCode(c *gin.Context, userID int) bool {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return false
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return false
+	}
+
+	enrollment, err := h.mfaRepo.Enrollment(userID)
+	if err == models.ErrMFANotEnrolled || (err == nil && enrollment.EnabledAt == nil) {
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrMFANotEnrolled.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+
+	ok, err := matchMFACode(h.mfaRepo, enrollment, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid authentication code"})
+		return false
+	}
+
+	return true
+}
+
+// GetStatus returns the requesting user's MFA status
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID := currentUser(c)
+	role, _ := c.Get("role")
+
+	status, err := h.mfaRepo.Status(userID, role.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll generates a new TOTP secret for the requesting user. MFA is not
+// enabled until the secret is confirmed with a code.
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID := currentUser(c)
+	username, _ := c.Get("username")
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	if err := h.mfaRepo.Begin(userID, secret); err == models.ErrMFAAlreadyEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(h.issuer, username.(string), secret),
+	})
+}
+
+// ConfirmEnrollment enables MFA once the user proves their authenticator
+// works, and returns the recovery codes. They are shown only this once.
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID := currentUser(c)
+
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	enrollment, err := h.mfaRepo.Enrollment(userID)
+	if err == models.ErrMFANotEnrolled {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if enrollment.EnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrMFAAlreadyEnabled.Error()})
+		return
+	}
+
+	ok, err := matchMFACode(h.mfaRepo, enrollment, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, err := h.mfaRepo.Enable(userID)
+	if err == models.ErrMFANotEnrolled {
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrMFAAlreadyEnabled.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// Disable turns off MFA for the requesting user after checking a current
+// code. Users whose role requires MFA cannot turn it off.
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := currentUser(c)
+	role, _ := c.Get("role")
+
+	status, err := h.mfaRepo.Status(userID, role.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if status.Required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+
+	if !h.bindCode(c, userID) {
+		return
+	}
+
+	if err := h.mfaRepo.Disable(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the requesting user's recovery codes after
+// checking a current code
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := currentUser(c)
+
+	if !h.bindCode(c, userID) {
+		return
+	}
+
+	codes, err := h.mfaRepo.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// GetPolicy returns the roles that must use MFA (requires mfa:manage)
+func (h *MFAHandler) GetPolicy(c *gin.Context) {
+	roles, err := h.mfaRepo.RequiredRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"required_roles": roles})
+}
+
+// SetPolicy replaces the roles that must use MFA (requires mfa:manage).
+// Users in those roles without MFA are limited to enrolling from their next
+// token on.
+func (h *MFAHandler) SetPolicy(c *gin.Context) {
+	var req mfaPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	if err := h.mfaRepo.SetRequiredRoles(req.RequiredRoles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update MFA policy"})
+		return
+	}
+
+	h.GetPolicy(c)
+}
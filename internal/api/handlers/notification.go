@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// maxReminderRules is how many reminder rules a user may have
+const maxReminderRules = 10
+
+// Notification list page sizes
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 100
+)
+
+// NotificationHandler serves in-app notifications, reminder rules and
+// notification settings
+type NotificationHandler struct {
+	notificationRepo *models.NotificationRepository
+	reminderRepo     *models.ReminderRepository
+	validate         *validator.Validate
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationRepo *models.NotificationRepository, reminderRepo *models.ReminderRepository) *NotificationHandler {
+	return &NotificationHandler{
+		notificationRepo: notificationRepo,
+		reminderRepo:     reminderRepo,
+		validate:         validator.New(),
+	}
+}
+
+// reminderRuleRequest is the body of POST /api/notifications/rules and PUT
+// /api/notifications/rules/:id. Exactly one of minutes_before, e.g. 1440 for
+// "1 day before", and time_of_day, e.g. "09:00" for "at 9am on the due date",
+// must be given; days_before and timezone only apply to time_of_day.
+type reminderRuleRequest struct {
+	MinutesBefore *int     `json:"minutes_before" validate:"omitempty,min=0,max=525600"`
+	DaysBefore    int      `json:"days_before" validate:"min=0,max=365"`
+	TimeOfDay     *string  `json:"time_of_day"`
+	Timezone      string   `json:"timezone" validate:"max=64"`
+	Channels      []string `json:"channels"`
+	Active        *bool    `json:"active"`
+}
+
+// notificationSettingsRequest is the body of PUT /api/notifications/settings
+type notificationSettingsRequest struct {
+	OverdueChannels []string `json:"overdue_channels" validate:"required"`
+}
+
+// scheduleReminders recomputes the reminders of a task. Failures are
+// logged; the change itself is already saved.
+func scheduleReminders(reminderRepo *models.ReminderRepository, taskID int) {
+	if err := reminderRepo.ScheduleForTasks(taskID); err != nil {
+		logrus.WithError(err).WithField("task_id", taskID).Error("Failed to schedule reminders")
+	}
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// checkChannels validates a list of notification channels. It writes an
+// error response and returns false if one is unknown.
+func checkChannels(c *gin.Context, channels []string) bool {
+	for _, ch := range channels {
+		if !models.IsValidChannel(ch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification channel: " + ch})
+			return false
+		}
+	}
+	return true
+}
+
+// loadNotification finds the requesting user's notification named by :id.
+// It writes an error response and returns nil on failure.
+func (h *NotificationHandler) loadNotification(c *gin.Context) *models.Notification {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return nil
+	}
+
+	notification, err := h.notificationRepo.FindByID(id)
+	if err == sql.ErrNoRows || (err == nil && notification.UserID != currentUser(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification"})
+		return nil
+	}
+	return notification
+}
+
+// GetNotifications returns the requesting user's notifications, newest
+// first. Supports unread=true, and paging with limit and before, the ID of
+// the last notification of the previous page.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	unreadOnly := c.Query("unread") == "true"
+
+	limit := defaultNotificationLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxNotificationLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	var beforeID int64
+	if value := c.Query("before"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before ID"})
+			return
+		}
+		beforeID = id
+	}
+
+	notifications, err := h.notificationRepo.ListByUser(currentUser(c), unreadOnly, beforeID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// GetUnreadCount returns how many unread notifications the requesting user
+// has
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	count, err := h.notificationRepo.UnreadCount(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkRead marks a notification as read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	h.setRead(c, true)
+}
+
+// MarkUnread marks a notification as unread
+func (h *NotificationHandler) MarkUnread(c *gin.Context) {
+	h.setRead(c, false)
+}
+
+// setRead sets the read state of the notification named by :id
+func (h *NotificationHandler) setRead(c *gin.Context, read bool) {
+	notification := h.loadNotification(c)
+	if notification == nil {
+		return
+	}
+
+	if err := h.notificationRepo.SetRead(notification, read); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllRead marks all of the requesting user's notifications as read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	marked, err := h.notificationRepo.MarkAllRead(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// DeleteNotification removes a notification
+func (h *NotificationHandler) DeleteNotification(c *gin.Context) {
+	notification := h.loadNotification(c)
+	if notification == nil {
+		return
+	}
+
+	if err := h.notificationRepo.Delete(notification.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted successfully"})
+}
+
+// GetSettings returns the requesting user's notification settings
+func (h *NotificationHandler) GetSettings(c *gin.Context) {
+	settings, err := h.notificationRepo.GetSettings(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings saves the channels overdue notifications are sent through;
+// an empty list keeps them in-app only
+func (h *NotificationHandler) UpdateSettings(c *gin.Context) {
+	var req notificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	if !checkChannels(c, req.OverdueChannels) {
+		return
+	}
+
+	settings := &models.NotificationSettings{
+		UserID:          currentUser(c),
+		OverdueChannels: uniqueStrings(req.OverdueChannels),
+	}
+	if err := h.notificationRepo.SaveSettings(settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetReminderRules returns the requesting user's reminder rules
+func (h *NotificationHandler) GetReminderRules(c *gin.Context) {
+	rules, err := h.reminderRepo.ListRules(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// bindReminderRule reads and validates a reminder rule request into rule.
+// It writes an error response and returns false if the request is invalid.
+func (h *NotificationHandler) bindReminderRule(c *gin.Context, rule *models.ReminderRule) bool {
+	var req reminderRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return false
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return false
+	}
+	if (req.MinutesBefore == nil) == (req.TimeOfDay == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of minutes_before and time_of_day is required"})
+		return false
+	}
+	if !checkChannels(c, req.Channels) {
+		return false
+	}
+
+	rule.MinutesBefore = req.MinutesBefore
+	rule.DaysBefore = 0
+	rule.TimeOfDay = nil
+	rule.Timezone = "UTC"
+	if req.TimeOfDay != nil {
+		at, err := time.Parse("15:04", *req.TimeOfDay)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "time_of_day must be HH:MM"})
+			return false
+		}
+		timeOfDay := at.Format("15:04")
+		rule.TimeOfDay = &timeOfDay
+		rule.DaysBefore = req.DaysBefore
+
+		if req.Timezone != "" {
+			ok, err := h.reminderRepo.ValidTimezone(req.Timezone)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check time zone"})
+				return false
+			}
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone: " + req.Timezone})
+				return false
+			}
+			rule.Timezone = req.Timezone
+		}
+	}
+
+	rule.Channels = uniqueStrings(req.Channels)
+	rule.Active = req.Active == nil || *req.Active
+	return true
+}
+
+// scheduleUserReminders recomputes the requesting user's reminders after
+// their rules changed. Failures are logged; the rules are already saved.
+func (h *NotificationHandler) scheduleUserReminders(c *gin.Context) {
+	userID := currentUser(c)
+	if err := h.reminderRepo.ScheduleForUser(userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to schedule reminders")
+	}
+}
+
+// CreateReminderRule adds a reminder rule and schedules its reminders for
+// the user's tasks
+func (h *NotificationHandler) CreateReminderRule(c *gin.Context) {
+	userID := currentUser(c)
+	rule := &models.ReminderRule{UserID: userID}
+	if !h.bindReminderRule(c, rule) {
+		return
+	}
+
+	count, err := h.reminderRepo.CountRules(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder rule"})
+		return
+	}
+	if count >= maxReminderRules {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many reminder rules"})
+		return
+	}
+
+	if err := h.reminderRepo.CreateRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder rule"})
+		return
+	}
+	h.scheduleUserReminders(c)
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// loadReminderRule finds the requesting user's reminder rule named by :id.
+// It writes an error response and returns nil on failure.
+func (h *NotificationHandler) loadReminderRule(c *gin.Context) *models.ReminderRule {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reminder rule ID"})
+		return nil
+	}
+
+	rule, err := h.reminderRepo.FindRule(id, currentUser(c))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder rule not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder rule"})
+		return nil
+	}
+	return rule
+}
+
+// UpdateReminderRule replaces a reminder rule and reschedules the user's
+// reminders
+func (h *NotificationHandler) UpdateReminderRule(c *gin.Context) {
+	rule := h.loadReminderRule(c)
+	if rule == nil {
+		return
+	}
+	if !h.bindReminderRule(c, rule) {
+		return
+	}
+
+	if err := h.reminderRepo.UpdateRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder rule"})
+		return
+	}
+	h.scheduleUserReminders(c)
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteReminderRule removes a reminder rule and its pending reminders
+func (h *NotificationHandler) DeleteReminderRule(c *gin.Context) {
+	rule := h.loadReminderRule(c)
+	if rule == nil {
+		return
+	}
+
+	if err := h.reminderRepo.DeleteRule(rule.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reminder rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder rule deleted successfully"})
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/oidc"
+)
+
+// oidcStateTTL is how long a user has to finish signing in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCHandler handles single sign-on through OpenID Connect providers
+type OIDCHandler struct {
+	providers    map[string]*oidc.Provider
+	identityRepo *models.IdentityRepository
+	userRepo     *models.UserRepository
+	tokenRepo    *models.TokenRepository
+	auth         *AuthHandler
+}
+
+// NewOIDCHandler creates a new OIDC handler. Sessions are issued through
+// auth so that SSO logins follow the same verification and MFA rules as
+// password logins.
+func NewOIDCHandler(providers map[string]*oidc.Provider, identityRepo *models.IdentityRepository, userRepo *models.UserRepository, tokenRepo *models.TokenRepository, auth *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		auth:         auth,
+	}
+}
+
+// provider looks up the :provider path parameter. It writes an error
+// response and returns nil if there is no such provider.
+func (h *OIDCHandler) provider(c *gin.Context) *oidc.Provider {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return nil
+	}
+	return provider
+}
+
+// GetProviders lists the identity providers users can sign in with
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+// Login redirects the browser to the provider to sign in
+func (h *OIDCHandler) Login(c *gin.Context) {
+	provider := h.provider(c)
+	if provider == nil {
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", provider.Config().Name).Error("OIDC discovery failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	if err := h.identityRepo.CreateState(state, provider.Config().Name, verifier, nonce, oidcStateTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes sign-in when the provider redirects back with an
+// authorization code. The response is the same as for POST /login.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider := h.provider(c)
+	if provider == nil {
+		return
+	}
+	cfg := provider.Config()
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":          "Sign-in was denied by the identity provider",
+			"provider_error": providerErr,
+		})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	loginState, err := h.identityRepo.ConsumeState(state, cfg.Name)
+	if err == models.ErrInvalidLoginState {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", cfg.Name).Warn("OIDC code exchange failed")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in with the identity provider failed"})
+		return
+	}
+
+	user := h.resolveUser(c, provider, claims)
+	if user == nil {
+		return
+	}
+
+	// With a role claim configured the provider is the source of truth for roles
+	if cfg.RoleClaim != "" {
+		if role := provider.MapRole(claims); role != user.Role {
+			if err := h.userRepo.SetRole(user.ID, role); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+				return
+			}
+			user.Role = role
+		}
+	}
+
+	h.auth.completeLogin(c, user)
+}
+
+// resolveUser finds the local user for an external identity: an already
+// linked account, an account with the same verified email, which gets
+// linked, or a newly provisioned account. It writes an error response and
+// returns nil on failure.
+func (h *OIDCHandler) resolveUser(c *gin.Context, provider *oidc.Provider, claims *oidc.Claims) *models.User {
+	cfg := provider.Config()
+
+	identity, err := h.identityRepo.Find(cfg.Name, claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil
+	}
+	if identity != nil {
+		if err := h.identityRepo.Touch(identity.ID, claims.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return nil
+		}
+		user, err := h.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return nil
+		}
+		return user
+	}
+
+	if claims.Email == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider did not share an email address"})
+		return nil
+	}
+
+	existing, err := h.userRepo.FindByEmail(claims.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil
+	}
+
+	var user *models.User
+	switch {
+	case existing != nil && claims.EmailVerified:
+		if !h.adoptAccount(c, existing) {
+			return nil
+		}
+		user = existing
+	case existing != nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email exists; verify your email with the identity provider to link it"})
+		return nil
+	case !cfg.AllowSignup:
+		c.JSON(http.StatusForbidden, gin.H{"error": "No account is linked to this identity"})
+		return nil
+	default:
+		if user = h.provisionUser(c, provider, claims); user == nil {
+			return nil
+		}
+	}
+
+	identity = &models.Identity{
+		UserID:   user.ID,
+		Provider: cfg.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := h.identityRepo.Link(identity); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to link identity"})
+		return nil
+	}
+
+	return user
+}
+
+// adoptAccount prepares an existing account to be linked by verified email.
+// If the account never verified its email, whoever set its password was not
+// proven to own the address, so the password and sessions are dropped. It
+// writes an error response and returns false on failure.
+func (h *OIDCHandler) adoptAccount(c *gin.Context, user *models.User) bool {
+	if user.EmailVerifiedAt != nil {
+		return true
+	}
+
+	if err := h.userRepo.ClearPassword(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if err := h.tokenRepo.RevokeAllRefreshTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if err := h.userRepo.MarkEmailVerified(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return true
+}
+
+// provisionUser creates an account for a first-time SSO user. It writes an
+// error response and returns nil on failure.
+func (h *OIDCHandler) provisionUser(c *gin.Context, provider *oidc.Provider, claims *oidc.Claims) *models.User {
+	username, err := h.uniqueUsername(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil
+	}
+
+	user := &models.User{
+		Username: username,
+		Email:    claims.Email,
+		Role:     provider.MapRole(claims),
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	// A concurrent sign-up may have taken the username or email
+	if err := h.userRepo.CreateExternal(user); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create user"})
+		return nil
+	}
+	return user
+}
+
+// uniqueUsername derives an unused username from the preferred username or
+// email address in claims
+func (h *OIDCHandler) uniqueUsername(claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = claims.Email
+	}
+	base, _, _ = strings.Cut(base, "@")
+
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, base)
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = "user" + base
+	}
+
+	for i := 1; i <= 10; i++ {
+		candidate := base
+		if i > 1 {
+			candidate += strconv.Itoa(i)
+		}
+		existing, err := h.userRepo.FindByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
+
+// GetIdentities lists the identities linked to the requesting user
+func (h *OIDCHandler) GetIdentities(c *gin.Context) {
+	userID := currentUser(c)
+
+	identities, err := h.identityRepo.ListByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkIdentity removes one of the requesting user's identities. The last
+// identity of an account without a password cannot be removed.
+func (h *OIDCHandler) UnlinkIdentity(c *gin.Context) {
+	userID := currentUser(c)
+
+	id, err := strconv.Atoi(c.Param("identityID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	identities, err := h.identityRepo.ListByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if user.PasswordHash == "" && len(identities) == 1 && identities[0].ID == id {
+		c.JSON(http.StatusConflict, gin.H{"error": "Set a password with a password reset before unlinking your only sign-in method"})
+		return
+	}
+
+	if err := h.identityRepo.Unlink(id, userID); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// ProjectHandler handles projects and their membership
+type ProjectHandler struct {
+	projectRepo *models.ProjectRepository
+	userRepo    *models.UserRepository
+	validate    *validator.Validate
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(projectRepo *models.ProjectRepository, userRepo *models.UserRepository) *ProjectHandler {
+	return &ProjectHandler{
+		projectRepo: projectRepo,
+		userRepo:    userRepo,
+		validate:    validator.New(),
+	}
+}
+
+// memberRequest is the body of POST /api/projects/:id/members
+type memberRequest struct {
+	UserID int    `json:"user_id" validate:"required"`
+	Role   string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// roleRequest is the body of PUT /api/projects/:id/members/:userID
+type roleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// projectID parses the :id path parameter and checks that the requesting
+// user has at least level access to the project. It writes an error response
+// and returns false on failure.
+func (h *ProjectHandler) projectID(c *gin.Context, level models.AccessLevel) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, false
+	}
+
+	if _, err := h.projectRepo.FindByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return 0, false
+	}
+
+	if !authorizeProject(c, h.projectRepo, id, level) {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// CreateProject creates a project owned by the authenticated user
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	var project models.Project
+	if err := c.ShouldBindJSON(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(project); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if err := h.projectRepo.Create(&project, userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// GetProjects returns the projects the user belongs to, or all of them with
+// project:read:any
+func (h *ProjectHandler) GetProjects(c *gin.Context) {
+	var projects []models.Project
+	var err error
+	if can(c, rbac.ProjectReadAny) {
+		projects, err = h.projectRepo.ListAll()
+	} else {
+		projects, err = h.projectRepo.ListForUser(currentUser(c))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProject returns a single project
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessRead)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// UpdateProject renames a project or changes its description
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessManage)
+	if !ok {
+		return
+	}
+
+	var project models.Project
+	if err := c.ShouldBindJSON(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(project); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	existing, err := h.projectRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	existing.Name = project.Name
+	existing.Description = project.Description
+
+	if err := h.projectRepo.Update(existing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, existing)
+}
+
+// DeleteProject deletes a project together with its tasks
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessManage)
+	if !ok {
+		return
+	}
+
+	if err := h.projectRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// GetMembers lists the members of a project
+func (h *ProjectHandler) GetMembers(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessRead)
+	if !ok {
+		return
+	}
+
+	members, err := h.projectRepo.Members(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddMember adds a user to a project with the given role
+func (h *ProjectHandler) AddMember(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessManage)
+	if !ok {
+		return
+	}
+
+	var req memberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	if _, err := h.userRepo.FindByID(req.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.setMember(c, id, req.UserID, req.Role, http.StatusCreated)
+}
+
+// UpdateMember changes a member's role
+func (h *ProjectHandler) UpdateMember(c *gin.Context) {
+	id, ok := h.projectID(c, models.AccessManage)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	role, err := h.projectRepo.MemberRole(id, memberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch member"})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	h.setMember(c, id, memberID, req.Role, http.StatusOK)
+}
+
+// setMember saves a membership and responds with the member list
+func (h *ProjectHandler) setMember(c *gin.Context, projectID, userID int, role string, status int) {
+	err := h.projectRepo.SetMember(projectID, userID, role)
+	if err == models.ErrLastOwner {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save member"})
+		return
+	}
+
+	members, err := h.projectRepo.Members(projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	c.JSON(status, members)
+}
+
+// RemoveMember removes a user from a project. Members may always remove
+// themselves; removing others requires owner access.
+func (h *ProjectHandler) RemoveMember(c *gin.Context) {
+	memberID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	userID := currentUser(c)
+	level := models.AccessManage
+	if memberID == userID {
+		level = models.AccessRead
+	}
+
+	id, ok := h.projectID(c, level)
+	if !ok {
+		return
+	}
+
+	err = h.projectRepo.RemoveMember(id, memberID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if err == models.ErrLastOwner {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
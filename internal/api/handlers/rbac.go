@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// roleNamePattern restricts role names to lowercase identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// RBACHandler manages roles, their permissions and the roles of users
+type RBACHandler struct {
+	rbacRepo *models.RBACRepository
+	userRepo *models.UserRepository
+	policy   *rbac.Policy
+	validate *validator.Validate
+}
+
+// NewRBACHandler creates a new RBAC handler. policy is invalidated whenever
+// a role changes.
+func NewRBACHandler(rbacRepo *models.RBACRepository, userRepo *models.UserRepository, policy *rbac.Policy) *RBACHandler {
+	return &RBACHandler{
+		rbacRepo: rbacRepo,
+		userRepo: userRepo,
+		policy:   policy,
+		validate: validator.New(),
+	}
+}
+
+// createRoleRequest is the body of POST /api/roles
+type createRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=20"`
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions"`
+}
+
+// updateRoleRequest is the body of PUT /api/roles/:role
+type updateRoleRequest struct {
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions"`
+}
+
+// userRoleRequest is the body of PUT /api/users/:id/role
+type userRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// GetPermissions lists every permission that can be granted (requires role:manage)
+func (h *RBACHandler) GetPermissions(c *gin.Context) {
+	permissions, err := h.rbacRepo.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// GetRoles lists every role with its permissions (requires role:manage)
+func (h *RBACHandler) GetRoles(c *gin.Context) {
+	roles, err := h.rbacRepo.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// GetRole returns a single role with its permissions (requires role:manage)
+func (h *RBACHandler) GetRole(c *gin.Context) {
+	role, err := h.rbacRepo.FindRole(c.Param("role"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// CreateRole adds a role with the given permissions (requires role:manage)
+func (h *RBACHandler) CreateRole(c *gin.Context) {
+	var req createRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	if !roleNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role names may only contain lowercase letters, digits, '-' and '_'"})
+		return
+	}
+
+	role := &models.Role{Name: req.Name, Description: req.Description, Permissions: req.Permissions}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+
+	switch err := h.rbacRepo.CreateRole(role); err {
+	case nil:
+	case models.ErrRoleExists:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case models.ErrUnknownPermission:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	h.policy.Invalidate()
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole replaces a role's description and permissions (requires
+// role:manage). The admin role always keeps role:manage so that roles can
+// still be managed afterwards.
+func (h *RBACHandler) UpdateRole(c *gin.Context) {
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	role := &models.Role{Name: c.Param("role"), Description: req.Description, Permissions: req.Permissions}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	if role.Name == rbac.AdminRole && !containsString(role.Permissions, rbac.RoleManage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The admin role must keep " + rbac.RoleManage})
+		return
+	}
+
+	switch err := h.rbacRepo.UpdateRole(role); err {
+	case nil:
+	case sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	case models.ErrUnknownPermission:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	h.policy.Invalidate()
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole removes a custom role that no user has (requires role:manage)
+func (h *RBACHandler) DeleteRole(c *gin.Context) {
+	switch err := h.rbacRepo.DeleteRole(c.Param("role")); err {
+	case nil:
+	case sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	case models.ErrBuiltinRole, models.ErrRoleInUse:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	h.policy.Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// SetUserRole changes a user's role (requires user:manage). The change
+// applies from the user's next token on. Users cannot change their own role,
+// so an administrator cannot lock themselves out by accident.
+func (h *RBACHandler) SetUserRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req userRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	if id == currentUser(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	user, err := h.userRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	exists, err := h.rbacRepo.RoleExists(req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if err := h.userRepo.SetRole(user.ID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	user.Role = req.Role
+
+	c.JSON(http.StatusOK, user)
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
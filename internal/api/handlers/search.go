@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// SearchTasks runs a full-text search over the tasks visible to the
+// authenticated user, best match first. q accepts web search syntax: quoted
+// phrases, OR and -word. lang names the text search configuration the query
+// is stemmed with. The GetTasks filters narrow the search, and limit and
+// offset page through it.
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	search := models.TaskSearch{
+		Filter:   filter,
+		Query:    query,
+		Language: c.DefaultQuery("lang", models.DefaultLanguage),
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		search.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil || search.Offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+			return
+		}
+	}
+	if !h.checkLanguage(c, search.Language) {
+		return
+	}
+
+	// The same tasks are searched as GetTasks would list
+	if !can(c, rbac.TaskReadAny) {
+		uid := currentUser(c)
+		search.Filter.VisibleTo = &uid
+		search.Filter.AllProjects = grants(c).AnyProject >= models.AccessRead
+	}
+
+	page, err := h.taskRepo.Search(search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search tasks"})
+		return
+	}
+
+	tasks := make([]models.Task, len(page.Results))
+	for i := range page.Results {
+		tasks[i] = page.Results[i].Task
+	}
+	if err := h.labelRepo.AttachLabels(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task labels"})
+		return
+	}
+	if err := h.assignRepo.AttachAssignees(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task assignees"})
+		return
+	}
+	for i := range tasks {
+		page.Results[i].Task = tasks[i]
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// checkLanguage writes a bad request response and returns false if lang is
+// not a text search configuration
+func (h *TaskHandler) checkLanguage(c *gin.Context, lang string) bool {
+	valid, err := h.taskRepo.ValidLanguage(lang)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check language"})
+		return false
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown language, use a text search configuration such as english or simple"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+)
+
+// SeriesHandler handles recurring task series
+type SeriesHandler struct {
+	seriesRepo   *models.SeriesRepository
+	taskRepo     *models.TaskRepository
+	projectRepo  *models.ProjectRepository
+	reminderRepo *models.ReminderRepository
+	broker       *events.Broker
+	validate     *validator.Validate
+}
+
+// NewSeriesHandler creates a new series handler
+func NewSeriesHandler(seriesRepo *models.SeriesRepository, taskRepo *models.TaskRepository, projectRepo *models.ProjectRepository, reminderRepo *models.ReminderRepository, broker *events.Broker) *SeriesHandler {
+	return &SeriesHandler{
+		seriesRepo:   seriesRepo,
+		taskRepo:     taskRepo,
+		projectRepo:  projectRepo,
+		reminderRepo: reminderRepo,
+		broker:       broker,
+		validate:     validator.New(),
+	}
+}
+
+// editOccurrenceRequest is the body of PUT /api/tasks/:id/series
+type editOccurrenceRequest struct {
+	Scope string `json:"scope" validate:"required,oneof=this following"`
+	models.SeriesChanges
+}
+
+// loadSeries finds the series named by the :id path parameter and checks
+// that the requesting user owns it. It writes an error response and returns
+// false on failure.
+func (h *SeriesHandler) loadSeries(c *gin.Context) (*models.TaskSeries, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return nil, false
+	}
+
+	series, err := h.seriesRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+
+	if !authorizeSeries(c, series) {
+		return nil, false
+	}
+
+	return series, true
+}
+
+// authorizeSeries checks that the requesting user owns series or has
+// series:manage:any. It writes an error response and returns false otherwise.
+func authorizeSeries(c *gin.Context, series *models.TaskSeries) bool {
+	if series.UserID != currentUser(c) && !can(c, rbac.SeriesManageAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return false
+	}
+	return true
+}
+
+// CreateSeries creates a recurring task template
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var series models.TaskSeries
+	if err := c.ShouldBindJSON(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(series); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	// Occurrence times are stored without a zone, so anchor rules in UTC
+	series.DTStart = series.DTStart.UTC()
+	if _, err := series.Rule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	series.UserID = userID.(int)
+
+	// Occurrences are created in the project, which requires editor access
+	if series.ProjectID != nil && !authorizeProject(c, h.projectRepo, *series.ProjectID, models.AccessWrite) {
+		return
+	}
+
+	if series.Priority == "" {
+		series.Priority = models.PriorityNormal
+	}
+
+	if err := h.seriesRepo.Create(&series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
+
+// GetSeriesList returns the authenticated user's recurring series
+func (h *SeriesHandler) GetSeriesList(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	series, err := h.seriesRepo.ListByUser(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetSeries returns a series and the occurrences generated so far
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	occurrences, err := h.seriesRepo.Occurrences(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch occurrences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":      series,
+		"occurrences": occurrences,
+	})
+}
+
+// UpdateSeries changes the template and rule for future occurrences
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	var changes models.SeriesChanges
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(changes); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	changes.Apply(series)
+	if _, err := series.Rule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+
+	if err := h.seriesRepo.Update(series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries stops a series; tasks already generated are kept
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	if err := h.seriesRepo.Delete(series.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted successfully"})
+}
+
+// EditOccurrence edits a generated task either on its own ("this") or
+// together with the rest of its series ("following")
+func (h *SeriesHandler) EditOccurrence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	// Only users with write access can update the task
+	if !authorizeTask(c, h.projectRepo, task, models.AccessWrite) {
+		return
+	}
+
+	if task.SeriesID == nil || task.OccurrenceAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrNotRecurring.Error()})
+		return
+	}
+
+	var req editOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	if req.Scope == "this" {
+		if req.RRule != nil || req.LeadMinutes != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rrule and lead_minutes can only change with scope \"following\""})
+			return
+		}
+
+		req.ApplyToTask(task)
+		err := h.taskRepo.Update(task)
+		if err == models.ErrVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task was modified by someone else, reload and try again"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+		scheduleReminders(h.reminderRepo, task.ID)
+
+		publishTaskEvent(c, h.broker, h.projectRepo, events.TaskUpdated, task, task)
+
+		c.JSON(http.StatusOK, gin.H{"task": task})
+		return
+	}
+
+	// Changing the rest of the series is up to whoever may manage it
+	current, err := h.seriesRepo.FindByID(*task.SeriesID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if !authorizeSeries(c, current) {
+		return
+	}
+
+	if req.RRule != nil {
+		probe := models.TaskSeries{RRule: *req.RRule, DTStart: *task.OccurrenceAt}
+		if _, err := probe.Rule(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+			return
+		}
+	}
+
+	series, err := h.seriesRepo.SplitFollowing(task, &req.SeriesChanges)
+	if err == models.ErrNotRecurring {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	scheduleReminders(h.reminderRepo, task.ID)
+
+	updated, err := h.taskRepo.FindByID(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	publishTaskEvent(c, h.broker, h.projectRepo, events.TaskUpdated, updated, updated)
+
+	c.JSON(http.StatusOK, gin.H{
+		"task":   updated,
+		"series": series,
+	})
+}
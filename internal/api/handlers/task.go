@@ -1,26 +1,47 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/events"
 	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
 )
 
 // TaskHandler handles task-related requests
 type TaskHandler struct {
 	taskRepo     *models.TaskRepository
 	categoryRepo *models.CategoryRepository
+	labelRepo    *models.LabelRepository
+	workflowRepo *models.WorkflowRepository
+	depRepo      *models.DependencyRepository
+	projectRepo  *models.ProjectRepository
+	assignRepo   *models.AssignmentRepository
+	reminderRepo *models.ReminderRepository
+	broker       *events.Broker
 	validate     *validator.Validate
 }
 
-// NewTaskHandler creates a new task handler
-func NewTaskHandler(taskRepo *models.TaskRepository, categoryRepo *models.CategoryRepository) *TaskHandler {
+// NewTaskHandler creates a new task handler. Changes to tasks are published
+// to broker and reschedule their reminders.
+func NewTaskHandler(taskRepo *models.TaskRepository, categoryRepo *models.CategoryRepository, labelRepo *models.LabelRepository, workflowRepo *models.WorkflowRepository, depRepo *models.DependencyRepository, projectRepo *models.ProjectRepository, assignRepo *models.AssignmentRepository, reminderRepo *models.ReminderRepository, broker *events.Broker) *TaskHandler {
 	return &TaskHandler{
 		taskRepo:     taskRepo,
 		categoryRepo: categoryRepo,
+		labelRepo:    labelRepo,
+		workflowRepo: workflowRepo,
+		depRepo:      depRepo,
+		projectRepo:  projectRepo,
+		assignRepo:   assignRepo,
+		reminderRepo: reminderRepo,
+		broker:       broker,
 		validate:     validator.New(),
 	}
 }
@@ -43,18 +64,72 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 	// Set user ID from authenticated user
 	userID, _ := c.Get("userID")
 	task.UserID = userID.(int)
+	creatorID := userID.(int)
+	task.CreatedBy = &creatorID
+	
+	// Creating a task in a project requires editor access to it
+	if task.ProjectID != nil && !authorizeProject(c, h.projectRepo, *task.ProjectID, models.AccessWrite) {
+		return
+	}
 	
 	// Set default status if not provided
 	if task.Status == "" {
-		task.Status = "pending"
+		task.Status = models.StatusPending
+	}
+	
+	// The initial status must belong to the category's workflow
+	workflow, err := h.workflowRepo.ForCategory(task.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+	if !workflow.HasStatus(task.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status", "allowed": workflow.Statuses})
+		return
+	}
+	
+	// Set default priority if not provided
+	if task.Priority == "" {
+		task.Priority = models.PriorityNormal
 	}
 	
-	// Create the task
-	if err := h.taskRepo.Create(&task); err != nil {
+	if task.ParentID != nil && !h.checkParent(c, 0, *task.ParentID) {
+		return
+	}
+	
+	if task.Language != "" && !h.checkLanguage(c, task.Language) {
+		return
+	}
+	
+	// New tasks are assigned to their creator unless assignees are given
+	if task.Assignees == nil {
+		task.Assignees = []int{creatorID}
+	}
+	
+	// Create the task with its status history, labels and assignees
+	err = h.taskRepo.Create(&task)
+	if err == models.ErrLabelNotVisible {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown label in label_ids"})
+		return
+	}
+	if err == models.ErrInvalidAssignee {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
 	
+	// The labels are saved, load them for the response
+	task.LabelIDs = nil
+	if !h.saveLabels(c, &task) {
+		return
+	}
+	
+	publishTaskEvent(c, h.broker, h.projectRepo, events.TaskCreated, &task, task)
+	
+	c.Header("ETag", taskETag(&task))
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -74,13 +149,8 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 	
-	// Get the requesting user's ID and role
-	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("role")
-	
-	// Only task owner or admin can update the task
-	if existingTask.UserID != userID.(int) && userRole.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	// Only users with write access can update the task
+	if !authorizeTask(c, h.projectRepo, existingTask, models.AccessWrite) {
 		return
 	}
 	
@@ -91,17 +161,113 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 	
-	// Preserve the ID and user ID
+	// Validate the request
+	if err := h.validate.Struct(updatedTask); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	
+	// Preserve the ID, owner and creator. Assignees are changed through the
+	// assignment endpoints.
 	updatedTask.ID = id
 	updatedTask.UserID = existingTask.UserID
+	updatedTask.CreatedBy = existingTask.CreatedBy
+	updatedTask.Assignees = nil
+	
+	if updatedTask.Priority == "" {
+		updatedTask.Priority = existingTask.Priority
+	}
+	if updatedTask.Status == "" {
+		updatedTask.Status = existingTask.Status
+	}
+	if updatedTask.Language == "" {
+		updatedTask.Language = existingTask.Language
+	}
+	
+	h.saveTask(c, existingTask, &updatedTask)
+}
+
+// saveTask persists an edited copy of existing, enforcing If-Match and the
+// status workflow, and writes the updated task as the response
+func (h *TaskHandler) saveTask(c *gin.Context, existing, updated *models.Task) {
+	if !checkIfMatch(c, existing) {
+		return
+	}
+	
+	// Only write if nobody else has changed the task since it was read
+	updated.Version = existing.Version
+	
+	// Moving a task into another project requires editor access there too
+	if updated.ProjectID != nil && (existing.ProjectID == nil || *existing.ProjectID != *updated.ProjectID) {
+		if !authorizeProject(c, h.projectRepo, *updated.ProjectID, models.AccessWrite) {
+			return
+		}
+	}
+	
+	if updated.ParentID != nil && (existing.ParentID == nil || *existing.ParentID != *updated.ParentID) {
+		if !h.checkParent(c, existing.ID, *updated.ParentID) {
+			return
+		}
+	}
+	
+	if updated.Language != existing.Language && !h.checkLanguage(c, updated.Language) {
+		return
+	}
+	
+	if !h.checkTransition(c, updated.CategoryID, existing.Status, updated.Status) {
+		return
+	}
+	
+	// A task cannot be completed while anything blocking it is still open
+	if updated.Status == models.StatusDone && existing.Status != models.StatusDone {
+		blockers, err := h.depRepo.OpenBlockerIDs(existing.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check task dependencies"})
+			return
+		}
+		if len(blockers) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Task is blocked by open tasks", "blocked_by": blockers})
+			return
+		}
+	}
 	
 	// Update the task
-	if err := h.taskRepo.Update(&updatedTask); err != nil {
+	err := h.taskRepo.Update(updated)
+	if err == models.ErrVersionConflict {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task was modified by someone else, reload and try again"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, updatedTask)
+	if !h.recordStatusChange(c, existing.ID, existing.Status, updated.Status) {
+		return
+	}
+	
+	if !h.saveLabels(c, updated) {
+		return
+	}
+	
+	// Moving the due date or closing or reopening the task changes its reminders
+	if !sameTime(existing.DueDate, updated.DueDate) || existing.Status != updated.Status {
+		scheduleReminders(h.reminderRepo, existing.ID)
+	}
+	
+	// The response shows the saved assignees, whatever the body contained
+	tasks := []models.Task{*updated}
+	if err := h.assignRepo.AttachAssignees(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task assignees"})
+		return
+	}
+	updated.Assignees = tasks[0].Assignees
+	
+	publishTaskEvent(c, h.broker, h.projectRepo, events.TaskUpdated, updated, updated)
+	
+	c.Header("ETag", taskETag(updated))
+	c.JSON(http.StatusOK, updated)
 }
 
 // DeleteTask handles task deletion
@@ -120,13 +286,15 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 	
-	// Get the requesting user's ID and role
-	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("role")
+	// Only users allowed to delete the task can delete it
+	if !authorizeTaskDelete(c, h.projectRepo, existingTask) {
+		return
+	}
 	
-	// Only task owner or admin can delete the task
-	if existingTask.UserID != userID.(int) && userRole.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	// Assignees lose access with the task but should still hear of it
+	audience, err := h.projectRepo.TaskAudience(existingTask)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignees"})
 		return
 	}
 	
@@ -135,6 +303,7 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
 	}
+	publishEvent(c, h.broker, events.TaskDeleted, existingTask, audience, gin.H{"id": id})
 	
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
@@ -155,43 +324,331 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 	
-	// Get the requesting user's ID and role
-	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("role")
+	// Only users who can see the task may view it
+	if !authorizeTask(c, h.projectRepo, task, models.AccessRead) {
+		return
+	}
+	
+	etag := taskETag(task)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	
-	// Only task owner or admin can view the task
-	if task.UserID != userID.(int) && userRole.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	tasks := []models.Task{*task}
+	if err := h.labelRepo.AttachLabels(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task labels"})
+		return
+	}
+	if err := h.assignRepo.AttachAssignees(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task assignees"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, tasks[0])
 }
 
-// GetTasks returns all tasks for the authenticated user
+// GetTasks returns a page of tasks visible to the authenticated user.
+// Supports filtering by status, project_id, category_id, assignee, created_by, watching, priority, label_id, estimate
+// bounds, due/created/updated ranges and a free-text q, sorting via sort and order, and keyset pagination via cursor.
 func (h *TaskHandler) GetTasks(c *gin.Context) {
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	// Users with task:read:any see all tasks, others see their personal
+	// tasks and the tasks of projects they belong to, or of every project
+	// with project:read:any
+	if !can(c, rbac.TaskReadAny) {
+		uid := currentUser(c)
+		filter.VisibleTo = &uid
+		filter.AllProjects = grants(c).AnyProject >= models.AccessRead
+	}
+	
+	page, err := h.taskRepo.List(filter)
+	if err == models.ErrInvalidCursor {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	
+	if err := h.labelRepo.AttachLabels(page.Tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task labels"})
+		return
+	}
+	if err := h.assignRepo.AttachAssignees(page.Tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task assignees"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, page)
+}
+
+// parseTaskFilter reads list query parameters into a TaskFilter
+func parseTaskFilter(c *gin.Context) (models.TaskFilter, error) {
+	filter := models.TaskFilter{
+		Status: c.Query("status"),
+		Query:  strings.TrimSpace(c.Query("q")),
+		Sort:   c.DefaultQuery("sort", "due_date"),
+		Cursor: c.Query("cursor"),
+	}
+	
+	if !models.IsValidTaskSort(filter.Sort) {
+		return filter, errors.New("sort must be one of due_date, created_at, updated_at, title")
+	}
+	
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+	
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > models.MaxTaskPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", models.MaxTaskPageSize)
+		}
+		filter.Limit = limit
+	}
+	
+	if projectStr := c.Query("project_id"); projectStr != "" {
+		projectID, err := strconv.Atoi(projectStr)
+		if err != nil {
+			return filter, errors.New("Invalid project_id")
+		}
+		filter.ProjectID = &projectID
+	}
+	
+	userParams := []struct {
+		name   string
+		target **int
+	}{
+		{"assignee", &filter.AssigneeID},
+		{"created_by", &filter.CreatedBy},
+	}
+	for _, p := range userParams {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		value, err := parseUserQuery(c, raw)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be a user ID or \"me\"", p.name)
+		}
+		*p.target = &value
+	}
+	
+	if watching := c.Query("watching"); watching != "" {
+		on, err := strconv.ParseBool(watching)
+		if err != nil {
+			return filter, errors.New("watching must be true or false")
+		}
+		if on {
+			userID, _ := c.Get("userID")
+			watcherID := userID.(int)
+			filter.WatcherID = &watcherID
+		}
+	}
+	
+	if categoryStr := c.Query("category_id"); categoryStr != "" {
+		categoryID, err := strconv.Atoi(categoryStr)
+		if err != nil {
+			return filter, errors.New("Invalid category_id")
+		}
+		filter.CategoryID = &categoryID
+	}
+	
+	if priorityStr := c.Query("priority"); priorityStr != "" {
+		for _, p := range strings.Split(priorityStr, ",") {
+			if !models.IsValidPriority(p) {
+				return filter, errors.New("priority must be one of low, normal, high, urgent")
+			}
+			filter.Priorities = append(filter.Priorities, p)
+		}
+	}
+	
+	if labelStr := c.Query("label_id"); labelStr != "" {
+		for _, raw := range strings.Split(labelStr, ",") {
+			labelID, err := strconv.Atoi(raw)
+			if err != nil {
+				return filter, errors.New("Invalid label_id")
+			}
+			filter.LabelIDs = append(filter.LabelIDs, labelID)
+		}
+	}
+	
+	intParams := []struct {
+		name   string
+		target **int
+	}{
+		{"estimate_min", &filter.EstimateMin},
+		{"estimate_max", &filter.EstimateMax},
+	}
+	for _, p := range intParams {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return filter, fmt.Errorf("%s must be a non-negative number of minutes", p.name)
+		}
+		*p.target = &value
+	}
+	
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"due_after", &filter.DueAfter},
+		{"due_before", &filter.DueBefore},
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+	for _, p := range timeParams {
+		value, err := parseTimeQuery(c, p.name)
+		if err != nil {
+			return filter, err
+		}
+		*p.target = value
+	}
+	
+	return filter, nil
+}
+
+// parseUserQuery parses a user ID query value, where "me" means the
+// authenticated user
+func parseUserQuery(c *gin.Context, raw string) (int, error) {
+	if raw == "me" {
+		userID, _ := c.Get("userID")
+		return userID.(int), nil
+	}
+	return strconv.Atoi(raw)
+}
+
+// parseTimeQuery parses an RFC 3339 timestamp or YYYY-MM-DD date query parameter
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or YYYY-MM-DD date", name)
+}
+
+// checkTransition validates a status change against the category's workflow.
+// It writes an error response and returns false if the change is not allowed.
+func (h *TaskHandler) checkTransition(c *gin.Context, categoryID *int, from, to string) bool {
+	workflow, err := h.workflowRepo.ForCategory(categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return false
+	}
+	
+	switch workflow.ValidateTransition(from, to) {
+	case nil:
+		return true
+	case models.ErrInvalidStatus:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status", "allowed": workflow.Statuses})
+	default:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Status transition not allowed",
+			"from":    from,
+			"to":      to,
+			"allowed": workflow.Transitions[from],
+		})
+	}
+	return false
+}
+
+// recordStatusChange appends to the task's status history when the status
+// changed. It writes an error response and returns false on failure.
+func (h *TaskHandler) recordStatusChange(c *gin.Context, taskID int, from, to string) bool {
+	if from == to {
+		return true
+	}
+	
 	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("role")
+	if err := h.workflowRepo.RecordTransition(taskID, &from, to, userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record status history"})
+		return false
+	}
+	return true
+}
+
+// GetTaskHistory returns the status history of a task
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
 	
-	var tasks []models.Task
-	var err error
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
 	
-	// Admin can see all tasks, regular users only see their own
-	if userRole.(string) == "admin" {
-		tasks, err = h.taskRepo.ListAll()
-	} else {
-		tasks, err = h.taskRepo.ListByUser(userID.(int))
+	// Only users who can see the task may view its history
+	if !authorizeTask(c, h.projectRepo, task, models.AccessRead) {
+		return
 	}
 	
+	history, err := h.workflowRepo.History(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task history"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, history)
+}
+
+// saveLabels replaces the task's labels when label_ids was supplied and loads
+// the resulting labels onto the task. It writes an error response and returns
+// false on failure.
+func (h *TaskHandler) saveLabels(c *gin.Context, task *models.Task) bool {
+	if task.LabelIDs != nil {
+		err := h.labelRepo.SetTaskLabels(task.ID, task.UserID, task.LabelIDs)
+		if err == models.ErrLabelNotVisible {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown label in label_ids"})
+			return false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save task labels"})
+			return false
+		}
+		task.LabelIDs = nil
+	}
+	
+	tasks := []models.Task{*task}
+	if err := h.labelRepo.AttachLabels(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task labels"})
+		return false
+	}
+	task.Labels = tasks[0].Labels
+	
+	return true
 }
 
-// CreateCategory handles category creation (admin only)
+// CreateCategory handles category creation (requires category:manage)
 func (h *TaskHandler) CreateCategory(c *gin.Context) {
 	var category models.Category
 	if err := c.ShouldBindJSON(&category); err != nil {
@@ -226,7 +683,7 @@ func (h *TaskHandler) GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, categories)
 }
 
-// DeleteCategory handles category deletion (admin only)
+// DeleteCategory handles category deletion (requires category:manage)
 func (h *TaskHandler) DeleteCategory(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// PatchTask applies a JSON Merge Patch (RFC 7396) to a task. Only the fields
+// present in the body are changed; null clears optional fields. Clients
+// should send the ETag from a previous read in If-Match.
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/merge-patch+json"})
+		return
+	}
+
+	// Check if task exists
+	existingTask, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	// Only users with write access can update the task
+	if !authorizeTask(c, h.projectRepo, existingTask, models.AccessWrite) {
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body must be a JSON object"})
+		return
+	}
+
+	updatedTask := *existingTask
+	if err := mergeTaskPatch(&updatedTask, patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the result
+	if err := h.validate.Struct(updatedTask); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	h.saveTask(c, existingTask, &updatedTask)
+}
+
+// mergeTaskPatch applies the fields of a merge patch to task
+func mergeTaskPatch(task *models.Task, patch map[string]json.RawMessage) error {
+	for field, raw := range patch {
+		isNull := string(raw) == "null"
+
+		var err error
+		switch field {
+		case "title", "status", "priority", "language":
+			if isNull {
+				return fmt.Errorf("%s cannot be null", field)
+			}
+			var value string
+			err = json.Unmarshal(raw, &value)
+			switch field {
+			case "title":
+				task.Title = value
+			case "status":
+				task.Status = value
+			case "priority":
+				task.Priority = value
+			case "language":
+				task.Language = value
+			}
+		case "description":
+			task.Description = ""
+			if !isNull {
+				err = json.Unmarshal(raw, &task.Description)
+			}
+		case "project_id":
+			task.ProjectID = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &task.ProjectID)
+			}
+		case "parent_id":
+			task.ParentID = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &task.ParentID)
+			}
+		case "category_id":
+			task.CategoryID = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &task.CategoryID)
+			}
+		case "estimate_minutes":
+			task.EstimateMinutes = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &task.EstimateMinutes)
+			}
+		case "due_date":
+			task.DueDate = nil
+			if !isNull {
+				var dueDate time.Time
+				err = json.Unmarshal(raw, &dueDate)
+				task.DueDate = &dueDate
+			}
+		case "label_ids":
+			// null removes every label
+			task.LabelIDs = []int{}
+			if !isNull {
+				err = json.Unmarshal(raw, &task.LabelIDs)
+			}
+		default:
+			return fmt.Errorf("field %q cannot be patched", field)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid value for %s", field)
+		}
+	}
+	return nil
+}
+
+// taskETag returns the strong entity tag for the current version of a task
+func taskETag(task *models.Task) string {
+	return fmt.Sprintf(`"%d-%d"`, task.ID, task.Version)
+}
+
+// checkIfMatch enforces an If-Match precondition against the stored task.
+// It writes a 412 response and returns false if the precondition fails.
+func checkIfMatch(c *gin.Context, task *models.Task) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	current := taskETag(task)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == current {
+			return true
+		}
+	}
+
+	c.Header("ETag", current)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task was modified by someone else, reload and try again"})
+	return false
+}
@@ -1,24 +1,43 @@
 package handlers
 
 import (
+	"database/sql"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
 	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
 )
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	userRepo *models.UserRepository
+	userRepo     *models.UserRepository
+	tokenRepo    *models.TokenRepository
+	throttleRepo *models.LoginThrottleRepository
+	auditRepo    *models.AuditRepository
+	auth         *AuthHandler
+	validate     *validator.Validate
 }
 
-// NewUserHandler creates a new user handler
-func NewUserHandler(userRepo *models.UserRepository) *UserHandler {
-	return &UserHandler{userRepo: userRepo}
+// NewUserHandler creates a new user handler. Password checks, verification
+// emails and new sessions go through auth.
+func NewUserHandler(userRepo *models.UserRepository, tokenRepo *models.TokenRepository, throttleRepo *models.LoginThrottleRepository, auditRepo *models.AuditRepository, auth *AuthHandler) *UserHandler {
+	return &UserHandler{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		throttleRepo: throttleRepo,
+		auditRepo:    auditRepo,
+		auth:         auth,
+		validate:     validator.New(),
+	}
 }
 
-// GetUsers returns all users (admin only)
+// GetUsers returns all users (requires user:read:any)
 func (h *UserHandler) GetUsers(c *gin.Context) {
 	users, err := h.userRepo.List()
 	if err != nil {
@@ -38,12 +57,8 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 	
-	// Get the requesting user's ID and role
-	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("role")
-	
-	// Only admins can view other users' details
-	if userID.(int) != id && userRole.(string) != "admin" {
+	// Only users with user:read:any can view other users' details
+	if currentUser(c) != id && !can(c, rbac.UserReadAny) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
@@ -55,4 +70,352 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	}
 	
 	c.JSON(http.StatusOK, user)
-}
\ No newline at end of file
+}
+
+// UnlockUser clears a user's failed logins, lifting any lockout (requires user:manage)
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	
+	key := models.ThrottleKey{Scope: models.ThrottleUsername, Key: strings.ToLower(user.Username)}
+	cleared, err := h.throttleRepo.Reset(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	
+	adminID := currentUser(c)
+	err = h.auditRepo.Record(models.AuditEntry{
+		UserID:     &adminID,
+		Action:     models.AuditAccountUnlocked,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		Details:    map[string]interface{}{"username": user.Username, "had_failures": cleared},
+		IPAddress:  c.ClientIP(),
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to write audit log")
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// updateProfileRequest is the body of PATCH /api/users/me. Changing the email
+// address needs the current password of accounts that have one.
+type updateProfileRequest struct {
+	Username        *string `json:"username" validate:"omitempty,min=3,max=50"`
+	Email           *string `json:"email" validate:"omitempty,email,max=100"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// changePasswordRequest is the body of POST /api/users/me/password
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// deleteAccountRequest is the body of DELETE /api/users/me
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// deleteUserRequest is the body of DELETE /api/users/:id. Without
+// ReassignTo the user's personal tasks are deleted.
+type deleteUserRequest struct {
+	ReassignTo *int `json:"reassign_to"`
+}
+
+// GetMe returns the authenticated user
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, err := h.userRepo.FindByID(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, user)
+}
+
+// confirmPassword checks the requesting user's password before a sensitive
+// change. Accounts without a password sign in through an identity provider
+// and have nothing to confirm. Wrong passwords count as failed logins. It
+// writes an error response and returns false on failure.
+func (h *UserHandler) confirmPassword(c *gin.Context, user *models.User, password string) bool {
+	if user.PasswordHash == "" {
+		return true
+	}
+	
+	keys := throttleKeys(c, user.Username)
+	if !h.auth.loginAllowed(c, keys) {
+		return false
+	}
+	if !h.userRepo.CheckPassword(user, password) {
+		h.auth.recordLoginFailure(c, keys, user.Username, user)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return false
+	}
+	
+	return true
+}
+
+// UpdateMe changes the authenticated user's username or email address. A new
+// email address must be verified again.
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged && !h.confirmPassword(c, user, req.CurrentPassword) {
+		return
+	}
+	if req.Username != nil {
+		user.Username = *req.Username
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	
+	if err := h.userRepo.UpdateProfile(user); err == models.ErrDuplicateUser {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+	
+	if emailChanged {
+		h.auth.sendVerification(c, user)
+	}
+	
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword replaces the authenticated user's password after checking
+// the current one. Every other session is ended and a new one is returned.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.PasswordHash == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "This account has no password; set one with a password reset"})
+		return
+	}
+	if !h.confirmPassword(c, user, req.CurrentPassword) {
+		return
+	}
+	
+	if err := h.userRepo.SetPassword(user.ID, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := h.tokenRepo.RevokeAllRefreshTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end other sessions"})
+		return
+	}
+	h.auth.audit(models.AuditEntry{
+		UserID:     &user.ID,
+		Action:     models.AuditPasswordChanged,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		IPAddress:  c.ClientIP(),
+	})
+	
+	session := h.auth.startSession(c, user)
+	if session == nil {
+		return
+	}
+	
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteMe permanently deletes the authenticated user's account after
+// checking their password. Personal tasks are deleted; see
+// models.UserRepository.Delete for what happens to projects.
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !h.confirmPassword(c, user, req.Password) {
+		return
+	}
+	
+	h.deleteUser(c, user, nil, nil)
+}
+
+// DeleteUser permanently deletes another user's account, optionally handing
+// their tasks, series and projects to another user (requires user:manage)
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	
+	var req deleteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	
+	adminID := currentUser(c)
+	if id == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use DELETE /api/users/me to delete your own account"})
+		return
+	}
+	
+	user, err := h.userRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	
+	if req.ReassignTo != nil {
+		active := false
+		if *req.ReassignTo != id {
+			if active, err = h.userRepo.IsActive(*req.ReassignTo); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+		}
+		if !active {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be another active user"})
+			return
+		}
+	}
+	
+	h.deleteUser(c, user, req.ReassignTo, &adminID)
+}
+
+// deleteUser deletes user on behalf of actorID, which is nil for users
+// deleting themselves, and writes the response
+func (h *UserHandler) deleteUser(c *gin.Context, user *models.User, reassignTo *int, actorID *int) {
+	if err := h.userRepo.Delete(user.ID, reassignTo); err == models.ErrSoleOwner {
+		c.JSON(http.StatusConflict, gin.H{"error": "Transfer ownership of shared projects before deleting the account"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	
+	details := map[string]interface{}{"username": user.Username}
+	if reassignTo != nil {
+		details["reassigned_to"] = *reassignTo
+	}
+	h.auth.audit(models.AuditEntry{
+		UserID:     actorID,
+		Action:     models.AuditAccountDeleted,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		Details:    details,
+		IPAddress:  c.ClientIP(),
+	})
+	
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// DeactivateUser stops a user from signing in and ends their sessions
+// (requires user:manage). Access tokens already issued are rejected too.
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	h.setDeactivated(c, true)
+}
+
+// ReactivateUser lets a deactivated user sign in again (requires user:manage)
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	h.setDeactivated(c, false)
+}
+
+// setDeactivated deactivates or reactivates the user in the :id path
+// parameter and writes the response
+func (h *UserHandler) setDeactivated(c *gin.Context, deactivated bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	
+	adminID := currentUser(c)
+	if deactivated && id == adminID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot deactivate your own account"})
+		return
+	}
+	
+	if err := h.userRepo.SetDeactivated(id, deactivated); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	
+	action := models.AuditAccountReactivated
+	if deactivated {
+		action = models.AuditAccountDeactivated
+		if err := h.tokenRepo.RevokeAllRefreshTokens(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end sessions"})
+			return
+		}
+	}
+	h.auth.audit(models.AuditEntry{
+		UserID:     &adminID,
+		Action:     action,
+		EntityType: "user",
+		EntityID:   &id,
+		IPAddress:  c.ClientIP(),
+	})
+	
+	user, err := h.userRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, user)
+}
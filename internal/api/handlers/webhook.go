@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
+	"github.com/yourusername/Task_Management/internal/webhooks"
+)
+
+// deliveryLogLimit is how many deliveries the delivery log shows
+const deliveryLogLimit = 100
+
+// WebhookHandler lets users subscribe URLs to task events
+type WebhookHandler struct {
+	webhookRepo  *models.WebhookRepository
+	projectRepo  *models.ProjectRepository
+	outbox       *webhooks.Outbox
+	allowPrivate bool
+	validate     *validator.Validate
+}
+
+// NewWebhookHandler creates a new webhook handler. allowPrivate lets
+// webhooks target loopback, private and link-local addresses.
+func NewWebhookHandler(webhookRepo *models.WebhookRepository, projectRepo *models.ProjectRepository, outbox *webhooks.Outbox, allowPrivate bool) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:  webhookRepo,
+		projectRepo:  projectRepo,
+		outbox:       outbox,
+		allowPrivate: allowPrivate,
+		validate:     validator.New(),
+	}
+}
+
+// createWebhookRequest is the body of POST /api/webhooks. Without a
+// project the webhook receives the events of the user's personal tasks and
+// of tasks assigned to them. Without events it receives every event type.
+// A secret is generated unless one is given.
+type createWebhookRequest struct {
+	URL       string   `json:"url" validate:"required,url,max=2000"`
+	Secret    string   `json:"secret" validate:"omitempty,min=16,max=100"`
+	Events    []string `json:"events"`
+	ProjectID *int     `json:"project_id"`
+}
+
+// updateWebhookRequest is the body of PUT /api/webhooks/:id. The secret is
+// only replaced if one is given.
+type updateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2000"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=100"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active" validate:"required"`
+}
+
+// checkWebhookTarget validates a webhook's URL and event filter. It writes
+// an error response and returns false if they are invalid.
+func (h *WebhookHandler) checkWebhookTarget(c *gin.Context, rawURL string, eventTypes []string) bool {
+	if err := webhooks.CheckTarget(c.Request.Context(), rawURL, h.allowPrivate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	for _, t := range eventTypes {
+		if !events.ValidType(t) && t != webhooks.NotificationEvent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + t})
+			return false
+		}
+	}
+	return true
+}
+
+// loadWebhook finds the webhook named by :id. Users can only see their own
+// webhooks unless they have webhook:manage. It writes an error response and
+// returns nil on failure.
+func (h *WebhookHandler) loadWebhook(c *gin.Context) *models.Webhook {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil
+	}
+
+	webhook, err := h.webhookRepo.FindByID(id)
+	if err == sql.ErrNoRows || (err == nil && webhook.UserID != currentUser(c) && !can(c, rbac.WebhookManage)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		return nil
+	}
+	return webhook
+}
+
+// GetWebhooks lists the requesting user's webhooks
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	hooks, err := h.webhookRepo.ListByUser(currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+// GetWebhook returns a single webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook := h.loadWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook subscribes a URL to task events. Project webhooks can only
+// be added by owners of the project. The secret is only in this response.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	if !h.checkWebhookTarget(c, req.URL, req.Events) {
+		return
+	}
+
+	userID := currentUser(c)
+	if req.ProjectID != nil {
+		// Only membership counts; the webhook stops when its creator leaves
+		access, err := h.projectRepo.ProjectAccess(*req.ProjectID, userID, models.Grants{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if access < models.AccessManage {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only project owners can add project webhooks"})
+			return
+		}
+	}
+
+	webhook := &models.Webhook{
+		UserID:    userID,
+		ProjectID: req.ProjectID,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    uniqueStrings(req.Events),
+		Active:    true,
+	}
+	if err := h.webhookRepo.Create(webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+		"message": "Copy the secret now; it cannot be shown again",
+	})
+}
+
+// UpdateWebhook changes a webhook's URL, event filter, active flag and,
+// optionally, secret
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhook := h.loadWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	var req updateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+	if !h.checkWebhookTarget(c, req.URL, req.Events) {
+		return
+	}
+
+	webhook.URL = req.URL
+	webhook.Secret = req.Secret
+	webhook.Events = uniqueStrings(req.Events)
+	webhook.Active = *req.Active
+	if err := h.webhookRepo.Update(webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhook := h.loadWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	if err := h.webhookRepo.Delete(webhook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// PingWebhook queues a test delivery, whatever the webhook's event filter
+func (h *WebhookHandler) PingWebhook(c *gin.Context) {
+	webhook := h.loadWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	delivery, err := h.outbox.Ping(webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue test delivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// GetDeliveries returns the delivery log of a webhook, newest first
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	webhook := h.loadWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveries(webhook.ID, deliveryLogLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDeliveriesByStatus lists the deliveries of every webhook with the
+// status query parameter, dead by default, newest first (requires
+// webhook:manage)
+func (h *WebhookHandler) GetDeliveriesByStatus(c *gin.Context) {
+	status := c.DefaultQuery("status", models.DeliveryDead)
+	switch status {
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or dead"})
+		return
+	}
+
+	deliveries, err := h.webhookRepo.ListByStatus(status, deliveryLogLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts, typically
+// after it was dead-lettered and the receiver has been fixed (requires
+// webhook:manage)
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := h.outbox.Redeliver(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue delivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
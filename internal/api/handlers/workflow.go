@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// WorkflowHandler handles per-category status workflow configuration
+type WorkflowHandler struct {
+	workflowRepo *models.WorkflowRepository
+	categoryRepo *models.CategoryRepository
+	validate     *validator.Validate
+}
+
+// NewWorkflowHandler creates a new workflow handler
+func NewWorkflowHandler(workflowRepo *models.WorkflowRepository, categoryRepo *models.CategoryRepository) *WorkflowHandler {
+	return &WorkflowHandler{
+		workflowRepo: workflowRepo,
+		categoryRepo: categoryRepo,
+		validate:     validator.New(),
+	}
+}
+
+// addStatusRequest is the body of POST /api/categories/:id/workflow/statuses
+type addStatusRequest struct {
+	Name string `json:"name" validate:"required,max=20"`
+}
+
+// categoryID parses the category from the path and checks that it exists.
+// It writes an error response and returns false on failure.
+func (h *WorkflowHandler) categoryID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return 0, false
+	}
+
+	if _, err := h.categoryRepo.FindByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// GetWorkflow returns the effective workflow of a category
+func (h *WorkflowHandler) GetWorkflow(c *gin.Context) {
+	categoryID, ok := h.categoryID(c)
+	if !ok {
+		return
+	}
+
+	workflow, err := h.workflowRepo.ForCategory(&categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+
+	transitions, err := h.workflowRepo.ListTransitions(categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow":          workflow,
+		"extra_transitions": transitions,
+	})
+}
+
+// AddStatus adds an extra status to a category's workflow (requires category:manage)
+func (h *WorkflowHandler) AddStatus(c *gin.Context) {
+	categoryID, ok := h.categoryID(c)
+	if !ok {
+		return
+	}
+
+	var req addStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	if models.DefaultWorkflow().HasStatus(req.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Status is part of the default workflow"})
+		return
+	}
+
+	if err := h.workflowRepo.AddStatus(categoryID, req.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add status"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"category_id": categoryID, "name": req.Name})
+}
+
+// RemoveStatus removes an extra status from a category's workflow (requires category:manage)
+func (h *WorkflowHandler) RemoveStatus(c *gin.Context) {
+	categoryID, ok := h.categoryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.workflowRepo.RemoveStatus(categoryID, c.Param("status")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status removed successfully"})
+}
+
+// AddTransition allows an extra transition in a category's workflow (requires category:manage)
+func (h *WorkflowHandler) AddTransition(c *gin.Context) {
+	categoryID, ok := h.categoryID(c)
+	if !ok {
+		return
+	}
+
+	var transition models.StatusTransition
+	if err := c.ShouldBindJSON(&transition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Validate the request
+	if err := h.validate.Struct(transition); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors.Error()})
+		return
+	}
+
+	// Both ends of the transition must be statuses of this category
+	workflow, err := h.workflowRepo.ForCategory(&categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+	if !workflow.HasStatus(transition.FromStatus) || !workflow.HasStatus(transition.ToStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown status", "allowed": workflow.Statuses})
+		return
+	}
+
+	transition.CategoryID = categoryID
+	if err := h.workflowRepo.AddTransition(&transition); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add transition"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, transition)
+}
+
+// DeleteTransition removes an extra transition from a category's workflow (requires category:manage)
+func (h *WorkflowHandler) DeleteTransition(c *gin.Context) {
+	categoryID, ok := h.categoryID(c)
+	if !ok {
+		return
+	}
+
+	transitionID, err := strconv.Atoi(c.Param("transitionID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transition ID"})
+		return
+	}
+
+	err = h.workflowRepo.DeleteTransition(categoryID, transitionID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transition not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transition"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transition deleted successfully"})
+}
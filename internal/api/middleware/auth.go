@@ -3,16 +3,25 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 	
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/rbac"
 	"github.com/yourusername/Task_Management/internal/utils"
 )
 
-// AuthMiddleware handles JWT authentication
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware handles authentication with JWTs or personal access tokens,
+// rejecting revoked tokens and the tokens of deactivated or deleted users
+func AuthMiddleware(keys *utils.KeySet, tokenRepo *models.TokenRepository, userRepo *models.UserRepository, accessTokenRepo *models.AccessTokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		
+		// Browsers cannot set headers on EventSource and WebSocket connections
+		if token := c.Query("access_token"); authHeader == "" && token != "" && isStreamRequest(c) {
+			authHeader = "Bearer " + token
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
@@ -28,48 +37,251 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 		
 		tokenString := tokenParts[1]
-		claims, err := utils.ValidateToken(tokenString, cfg.JWTSecret)
+		if models.IsAccessToken(tokenString) {
+			if authenticateAccessToken(c, tokenString, userRepo, accessTokenRepo) {
+				c.Next()
+			}
+			return
+		}
+		
+		claims, err := utils.ValidateToken(tokenString, keys)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 		
+		// Tokens issued before revocation support have no ID and cannot be revoked
+		if claims.Id != "" {
+			revoked, err := tokenRepo.IsAccessTokenRevoked(claims.Id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+		
+		active, err := userRepo.IsActive(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+			c.Abort()
+			return
+		}
+		
 		// Set user info in the context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("emailVerified", !claims.Unverified)
+		c.Set("mfaPending", claims.MFAPending)
+		c.Set("tokenID", claims.Id)
+		c.Set("tokenExpiresAt", time.Unix(claims.ExpiresAt, 0))
+		
+		c.Next()
+	}
+}
+
+// authenticateAccessToken sets up the context for a request made with a
+// personal access token. Such requests never need an MFA step; the token was
+// created from a session that had passed it. It writes an error response,
+// aborts and returns false if the token or its user is not active.
+func authenticateAccessToken(c *gin.Context, tokenString string, userRepo *models.UserRepository, accessTokenRepo *models.AccessTokenRepository) bool {
+	token, err := accessTokenRepo.Authenticate(tokenString, c.ClientIP())
+	if err == models.ErrInvalidAccessToken {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+		c.Abort()
+		return false
+	}
+	
+	user, err := userRepo.FindByID(token.UserID)
+	if err != nil || user.DeactivatedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+		c.Abort()
+		return false
+	}
+	
+	c.Set("userID", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
+	c.Set("emailVerified", user.EmailVerifiedAt != nil)
+	c.Set("mfaPending", false)
+	c.Set("tokenID", "")
+	c.Set("tokenScopes", []string(token.Scopes))
+	if token.ExpiresAt != nil {
+		c.Set("tokenExpiresAt", *token.ExpiresAt)
+	}
+	return true
+}
+
+// isStreamRequest reports whether the request opens an event stream or a
+// WebSocket
+func isStreamRequest(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket") ||
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// tokenTaskRoutes are the API areas that personal access tokens without the
+// admin scope can reach
+var tokenTaskRoutes = []string{
+	"/api/tasks",
+	"/api/comments",
+	"/api/series",
+	"/api/projects",
+	"/api/labels",
+	"/api/categories",
+	"/api/events",
+	"/api/notifications",
+	"/api/search",
+}
+
+// RestrictTokenScopes limits requests made with personal access tokens to
+// what their scopes allow. Account security settings are out of reach of
+// every token so that a leaked token cannot take over the account.
+func RestrictTokenScopes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, isToken := c.Get("tokenScopes")
+		if !isToken {
+			c.Next()
+			return
+		}
+		scopes, _ := value.([]string)
 		
+		path := c.FullPath()
+		safe := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if strings.HasPrefix(path, "/api/account") || strings.HasPrefix(path, "/api/users/me/") || (path == "/api/users/me" && !safe) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot change account settings"})
+			c.Abort()
+			return
+		}
+		
+		allowed := false
+		for _, scope := range scopes {
+			switch scope {
+			case rbac.ScopeAdmin:
+				allowed = true
+			case rbac.ScopeTasksWrite:
+				allowed = allowed || tokenTaskRoute(path) || path == "/api/users/me"
+			case rbac.ScopeTasksRead:
+				allowed = allowed || safe && (tokenTaskRoute(path) || path == "/api/users/me")
+			}
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token scope does not allow this request"})
+			c.Abort()
+			return
+		}
+		
+		c.Next()
+	}
+}
+
+// tokenTaskRoute reports whether a route pattern is in tokenTaskRoutes
+func tokenTaskRoute(path string) bool {
+	for _, prefix := range tokenTaskRoutes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPermissions looks up the permissions of the user's role and stores them
+// in the context for RequirePermission and the handlers. Role changes apply
+// once the user's access token is refreshed, and at once for personal access
+// tokens.
+func LoadPermissions(policy *rbac.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		roleStr, _ := role.(string)
+		
+		perms, err := policy.Permissions(roleStr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			c.Abort()
+			return
+		}
+		
+		// Personal access tokens only keep what their scopes allow
+		if scopes, ok := c.Get("tokenScopes"); ok {
+			perms = perms.Scoped(scopes.([]string))
+		}
+		
+		c.Set("permissions", perms)
 		c.Next()
 	}
 }
 
-// RequireRole ensures the user has a specific role
-func RequireRole(roles ...string) gin.HandlerFunc {
+// RequirePermission ensures the user's role grants all of perms
+func RequirePermission(perms ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
+		granted, exists := c.Get("permissions")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
 		}
 		
-		roleStr, ok := userRole.(string)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		set, _ := granted.(rbac.Set)
+		if !set.Has(perms...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 			c.Abort()
 			return
 		}
 		
-		// Check if user's role is allowed
-		for _, role := range roles {
-			if roleStr == role {
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail restricts users whose email address is not verified
+// according to policy: read_only lets them make only safe requests, block
+// rejects them entirely
+func RequireVerifiedEmail(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verified, _ := c.Get("emailVerified")
+		if verified == true || policy == config.UnverifiedAllow {
+			c.Next()
+			return
+		}
+		
+		if policy == config.UnverifiedReadOnly {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
 				c.Next()
 				return
 			}
 		}
 		
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address must be verified"})
 		c.Abort()
 	}
-}
\ No newline at end of file
+}
+
+// RequireMFAEnrollment confines users who must set up MFA before doing
+// anything else to the routes under enrollPrefix
+func RequireMFAEnrollment(enrollPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pending, _ := c.Get("mfaPending")
+		if pending != true || strings.HasPrefix(c.FullPath(), enrollPrefix) {
+			c.Next()
+			return
+		}
+		
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication must be set up first"})
+		c.Abort()
+	}
+}
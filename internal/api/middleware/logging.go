@@ -19,7 +19,10 @@ type responseWriter struct {
 }
 
 func (w responseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	// Event streams stay open indefinitely, so they are not captured
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -80,8 +83,8 @@ func AuditLogger(db *sqlx.DB) gin.HandlerFunc {
 		// Process the request first
 		c.Next()
 		
-		// Only log specific actions (POST, PUT, DELETE) for auditing
-		if c.Request.Method != "POST" && c.Request.Method != "PUT" && c.Request.Method != "DELETE" {
+		// Only log specific actions (POST, PUT, PATCH, DELETE) for auditing
+		if c.Request.Method != "POST" && c.Request.Method != "PUT" && c.Request.Method != "PATCH" && c.Request.Method != "DELETE" {
 			return
 		}
 		
@@ -114,16 +117,16 @@ func AuditLogger(db *sqlx.DB) gin.HandlerFunc {
 		switch c.Request.Method {
 		case "POST":
 			action = "create"
-		case "PUT":
+		case "PUT", "PATCH":
 			action = "update"
 		case "DELETE":
 			action = "delete"
 		}
 		
-		// Insert audit log
+		// Insert audit log; the user is gone after deleting their own account
 		_, err := db.Exec(
 			`INSERT INTO audit_logs (user_id, action, entity_type, entity_id, ip_address, created_at)
-			 VALUES ($1, $2, $3, $4, $5, NOW())`,
+			 VALUES ((SELECT id FROM users WHERE id = $1), $2, $3, $4, $5, NOW())`,
 			userID, action, entityType, entityID, c.ClientIP(),
 		)
 		
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 	
 	"github.com/gin-gonic/gin"
@@ -37,9 +38,9 @@ func RateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 		
 		// Set headers to inform client of rate limit status
-		c.Header("X-RateLimit-Limit", string(context.Limit))
-		c.Header("X-RateLimit-Remaining", string(context.Remaining))
-		c.Header("X-RateLimit-Reset", string(context.Reset))
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(context.Limit, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(context.Remaining, 10))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(context.Reset, 10))
 		
 		// If rate limit exceeded, return 429 Too Many Requests
 		if context.Reached {
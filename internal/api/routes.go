@@ -1,17 +1,27 @@
 package api
 
 import (
+	"time"
+	
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/Task_Management/internal/api/handlers"
 	"github.com/yourusername/Task_Management/internal/api/middleware"
 	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/mail"
 	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/oidc"
+	"github.com/yourusername/Task_Management/internal/rbac"
+	"github.com/yourusername/Task_Management/internal/storage"
+	"github.com/yourusername/Task_Management/internal/utils"
+	"github.com/yourusername/Task_Management/internal/webhooks"
 )
 
-// SetupRouter configures the API routes
-func SetupRouter(cfg *config.Config, db *sqlx.DB) *gin.Engine {
+// SetupRouter configures the API routes. Task and comment changes are
+// published to broker; outbox queues webhook deliveries.
+func SetupRouter(cfg *config.Config, db *sqlx.DB, store storage.Storage, keys *utils.KeySet, mailer mail.Mailer, broker *events.Broker, outbox *webhooks.Outbox) *gin.Engine {
 	// Create a new Gin router
 	router := gin.New()
 	
@@ -26,17 +36,63 @@ func SetupRouter(cfg *config.Config, db *sqlx.DB) *gin.Engine {
 	
 	// Create repositories
 	userRepo := models.NewUserRepository(db)
+	tokenRepo := models.NewTokenRepository(db)
+	userTokenRepo := models.NewUserTokenRepository(db)
+	mfaRepo := models.NewMFARepository(db)
+	identityRepo := models.NewIdentityRepository(db)
+	throttleRepo := models.NewLoginThrottleRepository(db)
+	auditRepo := models.NewAuditRepository(db)
+	rbacRepo := models.NewRBACRepository(db)
+	accessTokenRepo := models.NewAccessTokenRepository(db)
 	taskRepo := models.NewTaskRepository(db)
 	categoryRepo := models.NewCategoryRepository(db)
+	labelRepo := models.NewLabelRepository(db)
+	workflowRepo := models.NewWorkflowRepository(db)
+	depRepo := models.NewDependencyRepository(db)
+	seriesRepo := models.NewSeriesRepository(db)
+	projectRepo := models.NewProjectRepository(db)
+	assignRepo := models.NewAssignmentRepository(db)
+	commentRepo := models.NewCommentRepository(db)
+	attachRepo := models.NewAttachmentRepository(db)
+	webhookRepo := models.NewWebhookRepository(db)
+	reminderRepo := models.NewReminderRepository(db)
+	notificationRepo := models.NewNotificationRepository(db)
+	
+	// Role permissions are cached briefly so checks do not hit the database
+	policy := rbac.NewPolicy(rbacRepo, 30*time.Second)
 	
 	// Create handlers
-	authHandler := handlers.NewAuthHandler(userRepo, cfg)
-	userHandler := handlers.NewUserHandler(userRepo)
-	taskHandler := handlers.NewTaskHandler(taskRepo, categoryRepo)
+	authHandler := handlers.NewAuthHandler(userRepo, tokenRepo, userTokenRepo, mfaRepo, throttleRepo, auditRepo, keys, mailer, cfg)
+	userHandler := handlers.NewUserHandler(userRepo, tokenRepo, throttleRepo, auditRepo, authHandler)
+	mfaHandler := handlers.NewMFAHandler(mfaRepo, userRepo, cfg.MFAIssuer)
+	rbacHandler := handlers.NewRBACHandler(rbacRepo, userRepo, policy)
+	accessTokenHandler := handlers.NewAccessTokenHandler(accessTokenRepo)
+	oidcHandler := handlers.NewOIDCHandler(oidc.NewProviders(cfg.OIDCProviders), identityRepo, userRepo, tokenRepo, authHandler)
+	taskHandler := handlers.NewTaskHandler(taskRepo, categoryRepo, labelRepo, workflowRepo, depRepo, projectRepo, assignRepo, reminderRepo, broker)
+	workflowHandler := handlers.NewWorkflowHandler(workflowRepo, categoryRepo)
+	seriesHandler := handlers.NewSeriesHandler(seriesRepo, taskRepo, projectRepo, reminderRepo, broker)
+	labelHandler := handlers.NewLabelHandler(labelRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo, userRepo)
+	commentHandler := handlers.NewCommentHandler(commentRepo, taskRepo, userRepo, projectRepo, broker)
+	attachmentHandler := handlers.NewAttachmentHandler(attachRepo, taskRepo, projectRepo, store, cfg)
+	eventHandler := handlers.NewEventHandler(broker)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, projectRepo, outbox, cfg.Webhooks.AllowPrivateTargets)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo, reminderRepo)
 	
 	// Public routes
 	router.POST("/register", authHandler.Register)
 	router.POST("/login", authHandler.Login)
+	router.POST("/login/mfa", authHandler.LoginMFA)
+	router.POST("/refresh", authHandler.Refresh)
+	router.POST("/verify-email", authHandler.VerifyEmail)
+	router.POST("/verify-email/resend", authHandler.ResendVerification)
+	router.POST("/password-reset", authHandler.RequestPasswordReset)
+	router.POST("/password-reset/confirm", authHandler.ResetPassword)
+	router.GET("/auth/oidc/providers", oidcHandler.GetProviders)
+	router.GET("/auth/oidc/:provider/login", oidcHandler.Login)
+	router.GET("/auth/oidc/:provider/callback", oidcHandler.Callback)
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+	router.POST("/logout", middleware.AuthMiddleware(keys, tokenRepo, userRepo, accessTokenRepo), authHandler.Logout)
 	
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -45,24 +101,148 @@ func SetupRouter(cfg *config.Config, db *sqlx.DB) *gin.Engine {
 	
 	// Protected routes
 	api := router.Group("/api")
-	api.Use(middleware.AuthMiddleware(cfg))
+	api.Use(middleware.AuthMiddleware(keys, tokenRepo, userRepo, accessTokenRepo))
+	api.Use(middleware.RequireVerifiedEmail(cfg.UnverifiedPolicy))
+	api.Use(middleware.RequireMFAEnrollment("/api/account/mfa"))
+	api.Use(middleware.RestrictTokenScopes())
+	api.Use(middleware.LoadPermissions(policy))
 	api.Use(middleware.AuditLogger(db))
 	
 	// User routes
-	api.GET("/users", middleware.RequireRole("admin"), userHandler.GetUsers)
+	api.GET("/users", middleware.RequirePermission(rbac.UserReadAny), userHandler.GetUsers)
+	api.GET("/users/me", userHandler.GetMe)
+	api.PATCH("/users/me", userHandler.UpdateMe)
+	api.POST("/users/me/password", userHandler.ChangePassword)
+	api.DELETE("/users/me", userHandler.DeleteMe)
 	api.GET("/users/:id", userHandler.GetUser)
+	api.DELETE("/users/:id", middleware.RequirePermission(rbac.UserManage), userHandler.DeleteUser)
+	api.POST("/users/:id/unlock", middleware.RequirePermission(rbac.UserManage), userHandler.UnlockUser)
+	api.POST("/users/:id/deactivate", middleware.RequirePermission(rbac.UserManage), userHandler.DeactivateUser)
+	api.POST("/users/:id/reactivate", middleware.RequirePermission(rbac.UserManage), userHandler.ReactivateUser)
+	api.PUT("/users/:id/role", middleware.RequirePermission(rbac.UserManage), rbacHandler.SetUserRole)
+	
+	// Role and permission routes
+	api.GET("/permissions", middleware.RequirePermission(rbac.RoleManage), rbacHandler.GetPermissions)
+	api.GET("/roles", middleware.RequirePermission(rbac.RoleManage), rbacHandler.GetRoles)
+	api.POST("/roles", middleware.RequirePermission(rbac.RoleManage), rbacHandler.CreateRole)
+	api.GET("/roles/:role", middleware.RequirePermission(rbac.RoleManage), rbacHandler.GetRole)
+	api.PUT("/roles/:role", middleware.RequirePermission(rbac.RoleManage), rbacHandler.UpdateRole)
+	api.DELETE("/roles/:role", middleware.RequirePermission(rbac.RoleManage), rbacHandler.DeleteRole)
+	
+	// Two-factor authentication routes
+	api.GET("/account/mfa", mfaHandler.GetStatus)
+	api.POST("/account/mfa/enroll", mfaHandler.Enroll)
+	api.POST("/account/mfa/confirm", mfaHandler.ConfirmEnrollment)
+	api.POST("/account/mfa/disable", mfaHandler.Disable)
+	api.POST("/account/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	api.GET("/account/identities", oidcHandler.GetIdentities)
+	api.DELETE("/account/identities/:identityID", oidcHandler.UnlinkIdentity)
+	
+	// Personal access token routes
+	api.GET("/account/tokens", accessTokenHandler.GetTokens)
+	api.POST("/account/tokens", accessTokenHandler.CreateToken)
+	api.DELETE("/account/tokens/:tokenID", accessTokenHandler.RevokeToken)
+	api.GET("/mfa/policy", middleware.RequirePermission(rbac.MFAManage), mfaHandler.GetPolicy)
+	api.PUT("/mfa/policy", middleware.RequirePermission(rbac.MFAManage), mfaHandler.SetPolicy)
 	
 	// Task routes
-	api.POST("/tasks", taskHandler.CreateTask)
+	api.POST("/tasks", middleware.RequirePermission(rbac.TaskCreate), taskHandler.CreateTask)
 	api.GET("/tasks", taskHandler.GetTasks)
+	api.GET("/search", taskHandler.SearchTasks)
+	api.GET("/tasks/next", taskHandler.GetNextTasks)
 	api.GET("/tasks/:id", taskHandler.GetTask)
 	api.PUT("/tasks/:id", taskHandler.UpdateTask)
+	api.PATCH("/tasks/:id", taskHandler.PatchTask)
 	api.DELETE("/tasks/:id", taskHandler.DeleteTask)
+	api.GET("/tasks/:id/history", taskHandler.GetTaskHistory)
+	api.GET("/tasks/:id/children", taskHandler.GetChildren)
+	api.GET("/tasks/:id/dependencies", taskHandler.GetDependencies)
+	api.POST("/tasks/:id/dependencies", taskHandler.AddDependency)
+	api.DELETE("/tasks/:id/dependencies/:dependsOnID", taskHandler.RemoveDependency)
+	api.PUT("/tasks/:id/series", seriesHandler.EditOccurrence)
+	api.GET("/tasks/:id/assignees", taskHandler.GetAssignees)
+	api.PUT("/tasks/:id/assignees", taskHandler.SetAssignees)
+	api.GET("/tasks/:id/assignees/history", taskHandler.GetAssignmentHistory)
+	api.POST("/tasks/:id/watch", taskHandler.WatchTask)
+	api.DELETE("/tasks/:id/watch", taskHandler.UnwatchTask)
+	
+	// Comment routes
+	api.GET("/tasks/:id/comments", commentHandler.GetComments)
+	api.POST("/tasks/:id/comments", commentHandler.CreateComment)
+	api.PUT("/tasks/:id/comments/:commentID", commentHandler.UpdateComment)
+	api.DELETE("/tasks/:id/comments/:commentID", commentHandler.DeleteComment)
+	api.GET("/tasks/:id/comments/:commentID/history", commentHandler.GetCommentHistory)
+	api.GET("/comments/mentions", commentHandler.GetMentions)
+	
+	// Event stream routes
+	api.GET("/events", eventHandler.Stream)
+	api.GET("/events/ws", eventHandler.WebSocket)
+	
+	// Attachment routes
+	api.GET("/tasks/:id/attachments", attachmentHandler.GetAttachments)
+	api.POST("/tasks/:id/attachments", attachmentHandler.UploadAttachment)
+	api.GET("/tasks/:id/attachments/:attachmentID", attachmentHandler.DownloadAttachment)
+	api.DELETE("/tasks/:id/attachments/:attachmentID", attachmentHandler.DeleteAttachment)
+	
+	// Recurring series routes
+	api.POST("/series", middleware.RequirePermission(rbac.TaskCreate), seriesHandler.CreateSeries)
+	api.GET("/series", seriesHandler.GetSeriesList)
+	api.GET("/series/:id", seriesHandler.GetSeries)
+	api.PUT("/series/:id", seriesHandler.UpdateSeries)
+	api.DELETE("/series/:id", seriesHandler.DeleteSeries)
+	
+	// Project routes
+	api.POST("/projects", projectHandler.CreateProject)
+	api.GET("/projects", projectHandler.GetProjects)
+	api.GET("/projects/:id", projectHandler.GetProject)
+	api.PUT("/projects/:id", projectHandler.UpdateProject)
+	api.DELETE("/projects/:id", projectHandler.DeleteProject)
+	api.GET("/projects/:id/members", projectHandler.GetMembers)
+	api.POST("/projects/:id/members", projectHandler.AddMember)
+	api.PUT("/projects/:id/members/:userID", projectHandler.UpdateMember)
+	api.DELETE("/projects/:id/members/:userID", projectHandler.RemoveMember)
+	
+	// Webhook routes
+	api.GET("/webhooks", webhookHandler.GetWebhooks)
+	api.POST("/webhooks", webhookHandler.CreateWebhook)
+	api.GET("/webhooks/:id", webhookHandler.GetWebhook)
+	api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+	api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	api.POST("/webhooks/:id/ping", webhookHandler.PingWebhook)
+	api.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+	api.GET("/webhook-deliveries", middleware.RequirePermission(rbac.WebhookManage), webhookHandler.GetDeliveriesByStatus)
+	api.POST("/webhook-deliveries/:id/redeliver", middleware.RequirePermission(rbac.WebhookManage), webhookHandler.Redeliver)
+	
+	// Notification routes
+	api.GET("/notifications", notificationHandler.GetNotifications)
+	api.GET("/notifications/unread-count", notificationHandler.GetUnreadCount)
+	api.POST("/notifications/read-all", notificationHandler.MarkAllRead)
+	api.POST("/notifications/:id/read", notificationHandler.MarkRead)
+	api.POST("/notifications/:id/unread", notificationHandler.MarkUnread)
+	api.DELETE("/notifications/:id", notificationHandler.DeleteNotification)
+	api.GET("/notifications/settings", notificationHandler.GetSettings)
+	api.PUT("/notifications/settings", notificationHandler.UpdateSettings)
+	api.GET("/notifications/rules", notificationHandler.GetReminderRules)
+	api.POST("/notifications/rules", notificationHandler.CreateReminderRule)
+	api.PUT("/notifications/rules/:id", notificationHandler.UpdateReminderRule)
+	api.DELETE("/notifications/rules/:id", notificationHandler.DeleteReminderRule)
 	
 	// Category routes
 	api.GET("/categories", taskHandler.GetCategories)
-	api.POST("/categories", middleware.RequireRole("admin"), taskHandler.CreateCategory)
-	api.DELETE("/categories/:id", middleware.RequireRole("admin"), taskHandler.DeleteCategory)
+	api.POST("/categories", middleware.RequirePermission(rbac.CategoryManage), taskHandler.CreateCategory)
+	api.DELETE("/categories/:id", middleware.RequirePermission(rbac.CategoryManage), taskHandler.DeleteCategory)
+	
+	// Category workflow routes
+	api.GET("/categories/:id/workflow", workflowHandler.GetWorkflow)
+	api.POST("/categories/:id/workflow/statuses", middleware.RequirePermission(rbac.CategoryManage), workflowHandler.AddStatus)
+	api.DELETE("/categories/:id/workflow/statuses/:status", middleware.RequirePermission(rbac.CategoryManage), workflowHandler.RemoveStatus)
+	api.POST("/categories/:id/workflow/transitions", middleware.RequirePermission(rbac.CategoryManage), workflowHandler.AddTransition)
+	api.DELETE("/categories/:id/workflow/transitions/:transitionID", middleware.RequirePermission(rbac.CategoryManage), workflowHandler.DeleteTransition)
+	
+	// Label routes
+	api.GET("/labels", labelHandler.GetLabels)
+	api.POST("/labels", labelHandler.CreateLabel)
+	api.DELETE("/labels/:id", labelHandler.DeleteLabel)
 	
 	return router
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RoleMapping assigns Role to users whose role claim contains Value
+type RoleMapping struct {
+	Value string
+	Role  string
+}
+
+// OIDCProviderConfig configures single sign-on through an OpenID Connect
+// identity provider
+type OIDCProviderConfig struct {
+	Name         string // used in the login URL, e.g. /auth/oidc/{name}/login
+	Issuer       string
+	ClientID     string
+	ClientSecret string // empty for public clients, which rely on PKCE alone
+	RedirectURL  string // this API's callback URL as registered with the provider
+	Scopes       []string
+	DefaultRole  string // role of users created on first login
+	// ID token claim holding the user's groups or roles; empty disables mapping
+	RoleClaim string
+	// Checked in order; the first mapping whose value is in the claim wins
+	RoleMap     []RoleMapping
+	AllowSignup bool // create users who have no account yet
+}
+
+// LoadOIDCProviders reads the providers named in OIDC_PROVIDERS. Each
+// provider NAME is configured through OIDC_NAME_* variables:
+//
+//	OIDC_NAME_ISSUER, OIDC_NAME_CLIENT_ID, OIDC_NAME_REDIRECT_URL (required)
+//	OIDC_NAME_CLIENT_SECRET
+//	OIDC_NAME_SCOPES         space-separated, default "openid email profile"
+//	OIDC_NAME_DEFAULT_ROLE   default "user"
+//	OIDC_NAME_ROLE_CLAIM     e.g. "groups"
+//	OIDC_NAME_ROLE_MAP       comma-separated value=role pairs, e.g. "task-admins=admin"
+//	OIDC_NAME_ALLOW_SIGNUP   "false" to only sign in existing users
+func LoadOIDCProviders() ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		env := func(key string) string { return strings.TrimSpace(os.Getenv(prefix + key)) }
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(env("ISSUER"), "/"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			RedirectURL:  env("REDIRECT_URL"),
+			Scopes:       strings.Fields(env("SCOPES")),
+			DefaultRole:  env("DEFAULT_ROLE"),
+			RoleClaim:    env("ROLE_CLAIM"),
+			AllowSignup:  env("ALLOW_SIGNUP") != "false",
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		if provider.DefaultRole == "" {
+			provider.DefaultRole = "user"
+		}
+
+		for _, pair := range strings.Split(env("ROLE_MAP"), ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			value, role, ok := strings.Cut(pair, "=")
+			if !ok || value == "" || role == "" {
+				return nil, fmt.Errorf("invalid %sROLE_MAP entry %q: expected value=role", prefix, pair)
+			}
+			provider.RoleMap = append(provider.RoleMap, RoleMapping{Value: value, Role: role})
+		}
+
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
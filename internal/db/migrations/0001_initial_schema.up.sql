@@ -0,0 +1,41 @@
+-- Initial schema. Uses IF NOT EXISTS so databases created by the old
+-- createTables bootstrap adopt this migration without changes.
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(50) UNIQUE NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	role VARCHAR(20) NOT NULL DEFAULT 'user',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(100) NOT NULL,
+	description TEXT,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE,
+	category_id INT REFERENCES categories(id) ON DELETE SET NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	due_date TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES users(id) ON DELETE SET NULL,
+	action VARCHAR(50) NOT NULL,
+	entity_type VARCHAR(50) NOT NULL,
+	entity_id INT,
+	details JSONB,
+	ip_address VARCHAR(50),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_category_id;
+DROP INDEX IF EXISTS idx_tasks_status;
+DROP INDEX IF EXISTS idx_tasks_user_updated_at;
+DROP INDEX IF EXISTS idx_tasks_user_created_at;
+DROP INDEX IF EXISTS idx_tasks_user_due_date;
@@ -0,0 +1,6 @@
+-- Indexes backing keyset pagination and filtering on GET /api/tasks
+CREATE INDEX IF NOT EXISTS idx_tasks_user_due_date ON tasks (user_id, (COALESCE(due_date, 'infinity'::timestamp)), id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_created_at ON tasks (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_updated_at ON tasks (user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks (status);
+CREATE INDEX IF NOT EXISTS idx_tasks_category_id ON tasks (category_id);
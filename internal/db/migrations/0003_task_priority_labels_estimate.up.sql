@@ -0,0 +1,26 @@
+ALTER TABLE tasks
+	ADD COLUMN priority VARCHAR(10) NOT NULL DEFAULT 'normal'
+		CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+	ADD COLUMN estimate_minutes INT CHECK (estimate_minutes >= 0);
+
+CREATE INDEX idx_tasks_priority ON tasks (priority);
+
+-- Labels with a NULL user_id are global and visible to everyone
+CREATE TABLE labels (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	color VARCHAR(7),
+	user_id INT REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_labels_user_name ON labels (user_id, name) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_labels_global_name ON labels (name) WHERE user_id IS NULL;
+
+CREATE TABLE task_labels (
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	label_id INT NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX idx_task_labels_label_id ON task_labels (label_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS task_status_history;
+DROP TABLE IF EXISTS category_status_transitions;
+DROP TABLE IF EXISTS category_statuses;
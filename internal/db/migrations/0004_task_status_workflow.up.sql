@@ -0,0 +1,33 @@
+-- Extra workflow states an admin has added for a category
+CREATE TABLE category_statuses (
+	id SERIAL PRIMARY KEY,
+	category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	name VARCHAR(20) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (category_id, name)
+);
+
+-- Extra allowed transitions for a category, on top of the default workflow
+CREATE TABLE category_status_transitions (
+	id SERIAL PRIMARY KEY,
+	category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	from_status VARCHAR(20) NOT NULL,
+	to_status VARCHAR(20) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (category_id, from_status, to_status)
+);
+
+CREATE TABLE task_status_history (
+	id SERIAL PRIMARY KEY,
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	from_status VARCHAR(20),
+	to_status VARCHAR(20) NOT NULL,
+	changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+	changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_status_history_task_id ON task_status_history (task_id, changed_at);
+
+-- Seed history so existing tasks have a starting point
+INSERT INTO task_status_history (task_id, from_status, to_status, changed_by, changed_at)
+SELECT id, NULL, status, user_id, created_at FROM tasks;
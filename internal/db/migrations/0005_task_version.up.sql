@@ -0,0 +1,2 @@
+-- Incremented on every update; backs ETag/If-Match optimistic locking
+ALTER TABLE tasks ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+ALTER TABLE tasks ADD COLUMN parent_id INT REFERENCES tasks(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tasks_parent_id ON tasks (parent_id);
+
+-- task_id is blocked by depends_on_id
+CREATE TABLE task_dependencies (
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	depends_on_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (task_id, depends_on_id),
+	CHECK (task_id <> depends_on_id)
+);
+
+CREATE INDEX idx_task_dependencies_depends_on_id ON task_dependencies (depends_on_id);
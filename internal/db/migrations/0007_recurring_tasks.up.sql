@@ -0,0 +1,26 @@
+-- A recurring task template; occurrences are materialized into tasks
+CREATE TABLE task_series (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	title VARCHAR(100) NOT NULL,
+	description TEXT,
+	category_id INT REFERENCES categories(id) ON DELETE SET NULL,
+	priority VARCHAR(10) NOT NULL DEFAULT 'normal'
+		CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+	estimate_minutes INT CHECK (estimate_minutes >= 0),
+	rrule VARCHAR(255) NOT NULL,
+	dtstart TIMESTAMP NOT NULL,
+	lead_minutes INT NOT NULL DEFAULT 0 CHECK (lead_minutes >= 0),
+	next_occurrence_at TIMESTAMP,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_series_next_occurrence ON task_series (next_occurrence_at) WHERE active;
+
+ALTER TABLE tasks
+	ADD COLUMN series_id INT REFERENCES task_series(id) ON DELETE SET NULL,
+	ADD COLUMN occurrence_at TIMESTAMP;
+
+CREATE INDEX idx_tasks_series_id ON tasks (series_id, occurrence_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_project_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS project_id;
+DROP TABLE IF EXISTS project_members;
+DROP TABLE IF EXISTS projects;
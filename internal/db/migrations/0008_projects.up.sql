@@ -0,0 +1,23 @@
+CREATE TABLE projects (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	description TEXT,
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE project_members (
+	project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX idx_project_members_user_id ON project_members (user_id);
+
+-- Tasks without a project are personal to their owner
+ALTER TABLE tasks ADD COLUMN project_id INT REFERENCES projects(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tasks_project_id ON tasks (project_id);
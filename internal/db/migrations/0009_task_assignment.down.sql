@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS task_watchers;
+DROP TABLE IF EXISTS task_assignment_history;
+DROP TABLE IF EXISTS task_assignees;
+ALTER TABLE tasks DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,40 @@
+-- user_id stays the owning user; created_by records who created the task
+ALTER TABLE tasks ADD COLUMN created_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+UPDATE tasks SET created_by = user_id;
+
+CREATE INDEX idx_tasks_created_by ON tasks (created_by);
+
+CREATE TABLE task_assignees (
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	assigned_by INT REFERENCES users(id) ON DELETE SET NULL,
+	assigned_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX idx_task_assignees_user_id ON task_assignees (user_id);
+
+-- Until now the owner was implicitly the assignee
+INSERT INTO task_assignees (task_id, user_id, assigned_by, assigned_at)
+SELECT id, user_id, user_id, created_at FROM tasks;
+
+CREATE TABLE task_assignment_history (
+	id SERIAL PRIMARY KEY,
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	action VARCHAR(10) NOT NULL CHECK (action IN ('assigned', 'unassigned')),
+	changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+	changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_assignment_history_task_id ON task_assignment_history (task_id, changed_at);
+
+CREATE TABLE task_watchers (
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX idx_task_watchers_user_id ON task_watchers (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS task_comment_revisions;
+DROP TABLE IF EXISTS task_comments;
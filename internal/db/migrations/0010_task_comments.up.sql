@@ -0,0 +1,31 @@
+CREATE TABLE task_comments (
+	id SERIAL PRIMARY KEY,
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	user_id INT REFERENCES users(id) ON DELETE SET NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	edited_at TIMESTAMP,
+	deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_task_comments_task_id ON task_comments (task_id, created_at);
+
+-- Previous bodies of edited comments
+CREATE TABLE task_comment_revisions (
+	id SERIAL PRIMARY KEY,
+	comment_id INT NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	edited_by INT REFERENCES users(id) ON DELETE SET NULL,
+	edited_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_comment_revisions_comment_id ON task_comment_revisions (comment_id, edited_at);
+
+CREATE TABLE comment_mentions (
+	comment_id INT NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions (user_id);
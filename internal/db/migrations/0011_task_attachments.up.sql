@@ -0,0 +1,19 @@
+-- Stored contents, keyed by SHA-256 so identical uploads share one object
+CREATE TABLE attachment_blobs (
+	sha256 CHAR(64) PRIMARY KEY,
+	size BIGINT NOT NULL,
+	content_type VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE task_attachments (
+	id SERIAL PRIMARY KEY,
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	filename VARCHAR(255) NOT NULL,
+	sha256 CHAR(64) NOT NULL REFERENCES attachment_blobs(sha256),
+	uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_attachments_task_id ON task_attachments (task_id);
+CREATE INDEX idx_task_attachments_sha256 ON task_attachments (sha256);
@@ -0,0 +1,22 @@
+-- Refresh tokens are stored as SHA-256 hashes. Every rotation issues a new
+-- token in the same family; presenting a used token revokes the family.
+CREATE TABLE refresh_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	family_id CHAR(32) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+-- Access tokens revoked before they expire, by JWT ID
+CREATE TABLE revoked_tokens (
+	jti VARCHAR(64) PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed are trusted as they are
+UPDATE users SET email_verified_at = created_at;
+
+-- Single-use tokens mailed to users, stored as SHA-256 hashes
+CREATE TABLE user_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	used_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id, purpose);
@@ -0,0 +1,35 @@
+-- TOTP enrollment per user. The secret is stored when enrollment starts and
+-- only takes effect once enabled_at is set. last_step holds the time step of
+-- the last accepted code so a code cannot be used twice.
+CREATE TABLE user_mfa (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL,
+	enabled_at TIMESTAMP,
+	last_step BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One-time recovery codes, stored as SHA-256 hashes
+CREATE TABLE mfa_recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash CHAR(64) NOT NULL,
+	used_at TIMESTAMP,
+	UNIQUE (user_id, code_hash)
+);
+
+-- Pending second login steps. Each attempt uses up one of a limited number.
+CREATE TABLE mfa_challenges (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Roles whose members must enroll in MFA
+CREATE TABLE mfa_required_roles (
+	role VARCHAR(20) PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
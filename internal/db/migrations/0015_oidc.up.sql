@@ -0,0 +1,23 @@
+-- External identities linked to local accounts, one per provider subject
+CREATE TABLE user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	last_login_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+-- Authorization requests in flight, keyed by a hash of the state parameter
+CREATE TABLE oidc_login_states (
+	state_hash CHAR(64) PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	nonce VARCHAR(128) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
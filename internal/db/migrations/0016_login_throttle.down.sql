@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_logs_action;
+DROP TABLE IF EXISTS login_failures;
@@ -0,0 +1,13 @@
+-- Failed logins per submitted username and per client IP. Usernames are
+-- tracked whether or not they exist so responses do not reveal which do.
+CREATE TABLE login_failures (
+	scope VARCHAR(10) NOT NULL CHECK (scope IN ('username', 'ip')),
+	key VARCHAR(100) NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMP NOT NULL,
+	next_attempt_at TIMESTAMP,
+	locked_until TIMESTAMP,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idx_audit_logs_action ON audit_logs (action, created_at);
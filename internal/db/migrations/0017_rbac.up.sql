@@ -0,0 +1,58 @@
+-- Roles map to named permissions. Built-in roles cannot be deleted.
+CREATE TABLE roles (
+	name VARCHAR(20) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	builtin BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE permissions (
+	name VARCHAR(50) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+	role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+	permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+	PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+	('task:create', 'Create tasks'),
+	('task:read:own', 'Read own personal tasks'),
+	('task:update:own', 'Edit own personal tasks'),
+	('task:delete:own', 'Delete own personal tasks'),
+	('task:read:any', 'Read every task'),
+	('task:update:any', 'Edit every task'),
+	('task:delete:any', 'Delete every task'),
+	('task:reassign:any', 'Change the assignees of every task'),
+	('project:read:any', 'See every project'),
+	('project:manage:any', 'Manage every project and its members'),
+	('series:manage:any', 'Manage every recurring series'),
+	('category:manage', 'Manage categories and their workflows'),
+	('label:manage', 'Create global labels and delete any label'),
+	('user:read:any', 'See every user'),
+	('user:manage', 'Manage users and unlock accounts'),
+	('mfa:manage', 'Set which roles must use MFA'),
+	('role:manage', 'Manage roles and their permissions');
+
+INSERT INTO roles (name, description, builtin) VALUES
+	('admin', 'Full access', TRUE),
+	('user', 'Works with their own tasks and the projects they belong to', TRUE);
+
+-- Existing role names keep working; they start without permissions
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions;
+
+INSERT INTO role_permissions (role, permission) VALUES
+	('user', 'task:create'),
+	('user', 'task:read:own'),
+	('user', 'task:update:own'),
+	('user', 'task:delete:own');
+
+ALTER TABLE users
+	ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- Deactivated users cannot sign in and their tokens are rejected
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP;
@@ -0,0 +1,17 @@
+-- Long-lived tokens users create for scripts. Only a hash of each token is
+-- stored; token_prefix identifies it in listings.
+CREATE TABLE personal_access_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	token_prefix VARCHAR(16) NOT NULL,
+	scopes TEXT[] NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	last_used_ip VARCHAR(50),
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
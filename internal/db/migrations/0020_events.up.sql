@@ -0,0 +1,9 @@
+-- Task and comment events streamed to clients. Rows are only kept long
+-- enough for API instances to share them and for clients to resume.
+CREATE TABLE events (
+	id BIGSERIAL PRIMARY KEY,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_created_at ON events (created_at);
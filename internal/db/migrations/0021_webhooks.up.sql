@@ -0,0 +1,46 @@
+-- Webhooks post task events to external URLs. A webhook with a project
+-- receives that project's events; one without receives the events of its
+-- owner's personal tasks and of tasks assigned to them. An empty events
+-- list subscribes to every event type.
+CREATE TABLE webhooks (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id INT REFERENCES projects(id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	secret VARCHAR(100) NOT NULL,
+	events TEXT[] NOT NULL DEFAULT '{}',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks (user_id);
+CREATE INDEX idx_webhooks_project_id ON webhooks (project_id);
+
+-- The outbox: one row per event and webhook, retried until delivered or
+-- given up on ("dead")
+CREATE TABLE webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event_id BIGINT,
+	event_type VARCHAR(50) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP,
+	last_attempt_at TIMESTAMP,
+	response_status INT,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	delivered_at TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_dead ON webhook_deliveries (id) WHERE status = 'dead';
+
+INSERT INTO permissions (name, description) VALUES
+	('webhook:manage', 'See every webhook and redeliver failed deliveries');
+
+INSERT INTO role_permissions (role, permission) VALUES
+	('admin', 'webhook:manage');
@@ -0,0 +1,24 @@
+-- Background jobs. Workers claim due pending jobs with FOR UPDATE SKIP
+-- LOCKED. While a job with a unique_key is pending or running, no other job
+-- with the same key can be queued.
+CREATE TABLE jobs (
+	id BIGSERIAL PRIMARY KEY,
+	kind VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+	unique_key VARCHAR(200),
+	attempts INT NOT NULL DEFAULT 0,
+	max_attempts INT NOT NULL,
+	run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_by VARCHAR(100),
+	locked_at TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_due ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_running ON jobs (locked_at) WHERE status = 'running';
+CREATE INDEX idx_jobs_finished_at ON jobs (finished_at) WHERE finished_at IS NOT NULL;
+CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs (unique_key) WHERE status IN ('pending', 'running');
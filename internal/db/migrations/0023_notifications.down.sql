@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_tasks_overdue_sweep;
+ALTER TABLE tasks DROP COLUMN IF EXISTS overdue_at;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS reminders;
+DROP TABLE IF EXISTS reminder_rules;
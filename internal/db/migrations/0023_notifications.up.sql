@@ -0,0 +1,64 @@
+-- Reminder rules say when users want to hear about tasks coming due: either
+-- minutes_before the due date, or at time_of_day in timezone, days_before
+-- the due date. Email and webhook channels come on top of the in-app
+-- notification.
+CREATE TABLE reminder_rules (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	minutes_before INT CHECK (minutes_before >= 0),
+	days_before INT NOT NULL DEFAULT 0 CHECK (days_before >= 0),
+	time_of_day TIME,
+	timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+	channels TEXT[] NOT NULL DEFAULT '{}',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	CHECK ((minutes_before IS NULL) <> (time_of_day IS NULL))
+);
+
+CREATE INDEX idx_reminder_rules_user_id ON reminder_rules (user_id);
+
+-- Reminders are the rules applied to tasks: one per task, rule and
+-- recipient, recomputed whenever the due date or the recipients change.
+-- Times are UTC, like due dates.
+CREATE TABLE reminders (
+	id BIGSERIAL PRIMARY KEY,
+	task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+	rule_id INT NOT NULL REFERENCES reminder_rules(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	remind_at TIMESTAMP NOT NULL,
+	sent_at TIMESTAMP,
+	UNIQUE (task_id, rule_id, user_id)
+);
+
+CREATE INDEX idx_reminders_due ON reminders (remind_at) WHERE sent_at IS NULL;
+CREATE INDEX idx_reminders_user_id ON reminders (user_id);
+
+-- Channels overdue notifications are sent through besides in-app
+CREATE TABLE notification_settings (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	overdue_channels TEXT[] NOT NULL DEFAULT '{email}',
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- In-app notifications; channels records where else each was sent
+CREATE TABLE notifications (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	task_id INT REFERENCES tasks(id) ON DELETE SET NULL,
+	kind VARCHAR(20) NOT NULL CHECK (kind IN ('reminder', 'overdue')),
+	title TEXT NOT NULL,
+	body TEXT NOT NULL DEFAULT '',
+	channels TEXT[] NOT NULL DEFAULT '{}',
+	read_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications (user_id, id);
+CREATE INDEX idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Set when the overdue sweep flags a task; cleared when its due date
+-- changes or it is closed
+ALTER TABLE tasks ADD COLUMN overdue_at TIMESTAMP;
+
+CREATE INDEX idx_tasks_overdue_sweep ON tasks (due_date) WHERE overdue_at IS NULL AND due_date IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_categories_search;
+ALTER TABLE categories DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_tasks_search;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tasks DROP COLUMN IF EXISTS language;
@@ -0,0 +1,16 @@
+-- Each task is stemmed with its own text search configuration
+ALTER TABLE tasks ADD COLUMN language REGCONFIG NOT NULL DEFAULT 'english';
+
+ALTER TABLE tasks ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+	setweight(to_tsvector(language, COALESCE(title, '')), 'A') ||
+	setweight(to_tsvector(language, COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_tasks_search ON tasks USING GIN (search_vector);
+
+-- Category names are shared by tasks in every language, so they are not stemmed
+ALTER TABLE categories ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+	to_tsvector('simple', name)
+) STORED;
+
+CREATE INDEX idx_categories_search ON categories USING GIN (search_vector);
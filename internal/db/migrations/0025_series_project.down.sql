@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_series_project_id;
+ALTER TABLE task_series DROP COLUMN IF EXISTS project_id;
@@ -0,0 +1,4 @@
+-- Occurrences of a project's recurring task belong to the project too
+ALTER TABLE task_series ADD COLUMN project_id INT REFERENCES projects(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_task_series_project_id ON task_series (project_id);
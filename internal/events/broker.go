@@ -0,0 +1,220 @@
+// Package events fans task and comment changes out to connected clients
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskDeleted    = "task.deleted"
+	CommentCreated = "comment.created"
+	CommentUpdated = "comment.updated"
+	CommentDeleted = "comment.deleted"
+)
+
+// ValidType reports whether t is a known event type
+func ValidType(t string) bool {
+	switch t {
+	case TaskCreated, TaskUpdated, TaskDeleted, CommentCreated, CommentUpdated, CommentDeleted:
+		return true
+	}
+	return false
+}
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped
+const subscriberBuffer = 64
+
+// Event is a change to a task or one of its comments. TaskID, ProjectID and
+// OwnerID describe the task, and MemberIDs and AssigneeIDs are the members of
+// its project and its assignees when the event was published, so that
+// subscribers can check who may see it without asking the database.
+type Event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	TaskID      int             `json:"task_id"`
+	ProjectID   *int            `json:"project_id"`
+	OwnerID     int             `json:"owner_id"`
+	ActorID     int             `json:"actor_id"`
+	MemberIDs   []int           `json:"member_ids,omitempty"`
+	AssigneeIDs []int           `json:"assignee_ids,omitempty"`
+	Data        json.RawMessage `json:"data"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Transport carries published events to the brokers of every API instance,
+// including the publishing one, which receive them through Deliver
+type Transport interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+// Sink receives each event once, from the instance that published it, after
+// the event has been given its ID. Sinks hand events to other systems, such
+// as webhooks, that must not see them once per instance.
+type Sink interface {
+	Accept(ctx context.Context, event *Event) error
+}
+
+// Broker delivers events to subscribers and keeps the most recent ones so
+// that clients can resume after reconnecting
+type Broker struct {
+	mu          sync.Mutex
+	transport   Transport
+	sinks       []Sink
+	lastID      int64
+	replay      []*Event
+	replaySize  int
+	seen        map[int64]bool
+	subscribers map[*Subscription]bool
+}
+
+// NewBroker creates a broker that keeps the last replaySize events
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		replaySize:  replaySize,
+		seen:        make(map[int64]bool),
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// SetTransport makes Publish send events through t. Without a transport
+// events only reach subscribers of this broker.
+func (b *Broker) SetTransport(t Transport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.transport = t
+}
+
+// AddSink makes Publish pass events to s
+func (b *Broker) AddSink(s Sink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sinks = append(b.sinks, s)
+}
+
+// Publish announces an event and passes it to the sinks. The transport
+// assigns its ID. The first error is returned, but every sink is tried.
+func (b *Broker) Publish(ctx context.Context, event *Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	transport, sinks := b.transport, b.sinks
+	if transport == nil {
+		event.ID = b.lastID + 1
+		b.deliver(event)
+	}
+	b.mu.Unlock()
+
+	if transport != nil {
+		if err := transport.Send(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	for _, sink := range sinks {
+		if err := sink.Accept(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Deliver hands an event to every subscriber and the replay buffer. Events
+// already delivered are ignored. Subscribers that have fallen too far behind
+// are dropped; they can resume from the replay buffer.
+func (b *Broker) Deliver(event *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliver(event)
+}
+
+// deliver is Deliver with b.mu held
+func (b *Broker) deliver(event *Event) {
+	if b.seen[event.ID] {
+		return
+	}
+	b.seen[event.ID] = true
+	if event.ID > b.lastID {
+		b.lastID = event.ID
+	}
+
+	b.replay = append(b.replay, event)
+	if len(b.replay) > b.replaySize {
+		delete(b.seen, b.replay[0].ID)
+		b.replay = b.replay[1:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// LastID returns the ID of the newest event delivered so far
+func (b *Broker) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Subscribe starts receiving events. With a lastEventID the events after it
+// are returned as a backlog; complete is false if some of them have already
+// left the replay buffer.
+func (b *Broker) Subscribe(lastEventID int64) (sub *Subscription, backlog []*Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 {
+		complete = false
+		for i, event := range b.replay {
+			if event.ID == lastEventID {
+				backlog = append(backlog, b.replay[i+1:]...)
+				complete = true
+				break
+			}
+		}
+		// Nothing was missed if no event has happened since
+		if !complete && lastEventID == b.lastID {
+			complete = true
+		}
+	}
+
+	ch := make(chan *Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, broker: b}
+	b.subscribers[sub] = true
+	return sub, backlog, complete
+}
+
+// Subscription receives events until it is closed. C is closed when the
+// subscriber is dropped for falling behind.
+type Subscription struct {
+	C      <-chan *Event
+	ch     chan *Event
+	broker *Broker
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[s] {
+		delete(b.subscribers, s)
+		close(s.ch)
+	}
+}
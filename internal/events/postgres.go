@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// notifyChannel is the Postgres channel new event IDs are announced on
+const notifyChannel = "task_events"
+
+// PGTransport shares events between API instances. Events are stored in the
+// events table, which also assigns their IDs, and announced with NOTIFY; every
+// instance LISTENs and delivers them to its broker.
+type PGTransport struct {
+	db        *sqlx.DB
+	dsn       string
+	broker    *Broker
+	retention time.Duration
+}
+
+// NewPGTransport creates a transport for broker. dsn is used for the
+// dedicated listening connection. Stored events are deleted after retention.
+func NewPGTransport(db *sqlx.DB, dsn string, broker *Broker, retention time.Duration) *PGTransport {
+	return &PGTransport{
+		db:        db,
+		dsn:       dsn,
+		broker:    broker,
+		retention: retention,
+	}
+}
+
+// Send stores an event and notifies every instance, including this one
+func (t *PGTransport) Send(ctx context.Context, event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return t.db.QueryRowxContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO events (payload, created_at) VALUES ($1, $2) RETURNING id
+		)
+		SELECT id FROM inserted, pg_notify($3, id::text)
+	`, payload, event.CreatedAt, notifyChannel).Scan(&event.ID)
+}
+
+// Run delivers announced events to the broker until ctx is cancelled. The
+// replay buffer is filled from the events table on start, and events missed
+// while the listening connection was down are caught up on reconnect.
+func (t *PGTransport) Run(ctx context.Context) {
+	listener := pq.NewListener(t.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logrus.WithError(err).Warn("Event listener connection problem")
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		logrus.WithError(err).Error("Failed to listen for events")
+		return
+	}
+	t.catchUp()
+
+	// Pings notice a silently dropped connection
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	purge := time.NewTicker(t.retention / 4)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect
+			if n == nil {
+				t.catchUp()
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				continue
+			}
+			t.load("id = $1", id)
+		case <-ping.C:
+			listener.Ping()
+		case <-purge.C:
+			t.purge()
+		}
+	}
+}
+
+// catchUp delivers the events stored since the newest one the broker has.
+// A broker that has none yet is filled with the most recent events.
+func (t *PGTransport) catchUp() {
+	lastID := t.broker.LastID()
+	if lastID == 0 {
+		t.load("id > (SELECT COALESCE(MAX(id), 0) FROM events) - $1", t.broker.replaySize)
+		return
+	}
+	t.load("id > $1", lastID)
+}
+
+// load delivers the stored events matching where in ID order
+func (t *PGTransport) load(where string, args ...interface{}) {
+	rows := []struct {
+		ID      int64  `db:"id"`
+		Payload []byte `db:"payload"`
+	}{}
+	if err := t.db.Select(&rows, "SELECT id, payload FROM events WHERE "+where+" ORDER BY id", args...); err != nil {
+		logrus.WithError(err).Error("Failed to load events")
+		return
+	}
+
+	for _, row := range rows {
+		event := &Event{}
+		if err := json.Unmarshal(row.Payload, event); err != nil {
+			logrus.WithError(err).WithField("event_id", row.ID).Error("Invalid stored event")
+			continue
+		}
+		event.ID = row.ID
+		t.broker.Deliver(event)
+	}
+}
+
+// purge deletes stored events older than the retention period
+func (t *PGTransport) purge() {
+	result, err := t.db.Exec("DELETE FROM events WHERE created_at < $1", time.Now().UTC().Add(-t.retention))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to purge events")
+		return
+	}
+	if purged, _ := result.RowsAffected(); purged > 0 {
+		logrus.WithField("count", purged).Info("Purged old events")
+	}
+}
@@ -0,0 +1,64 @@
+// Package jobs is a durable background job queue on the jobs table. Jobs
+// are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so any number of
+// workers, embedded in API instances or running as cmd/worker, may share it.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// DefaultMaxAttempts is how often a job is attempted unless its options say
+// otherwise
+const DefaultMaxAttempts = 5
+
+// Options control when and how often a job runs
+type Options struct {
+	// RunAt delays the job; zero means as soon as possible
+	RunAt time.Time
+	// UniqueKey, if set, prevents queueing the job while another job with
+	// the same key is pending or running
+	UniqueKey string
+	// MaxAttempts defaults to DefaultMaxAttempts
+	MaxAttempts int
+}
+
+// Queue adds jobs to the jobs table
+type Queue struct {
+	repo *models.JobRepository
+}
+
+// NewQueue creates a new queue
+func NewQueue(repo *models.JobRepository) *Queue {
+	return &Queue{repo: repo}
+}
+
+// Enqueue queues a job of the given kind with payload encoded as JSON. It
+// returns models.ErrDuplicateJob if opts.UniqueKey is taken.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload interface{}, opts Options) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	if err := q.repo.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// HandlerFunc runs a job. Returning an error retries the job with backoff
+// unless the error is Permanent or the job is out of attempts.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+// Registry maps job kinds to their handlers
+type Registry struct {
+	handlers map[string]HandlerFunc
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]HandlerFunc)}
+}
+
+// Register sets the handler of kind. Registering a kind twice is a
+// programming error and panics.
+func (r *Registry) Register(kind string, fn HandlerFunc) {
+	if _, ok := r.handlers[kind]; ok {
+		panic("jobs: handler for " + kind + " registered twice")
+	}
+	r.handlers[kind] = fn
+}
+
+// Kinds returns the registered kinds; workers only claim jobs of these
+func (r *Registry) Kinds() []string {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// handler returns the handler of kind, if any
+func (r *Registry) handler(kind string) (HandlerFunc, bool) {
+	fn, ok := r.handlers[kind]
+	return fn, ok
+}
+
+// Kind is a job kind whose payload has type T
+type Kind[T any] struct {
+	Name string
+}
+
+// NewKind declares a job kind with payload type T
+func NewKind[T any](name string) Kind[T] {
+	return Kind[T]{Name: name}
+}
+
+// Enqueue queues a job of this kind
+func (k Kind[T]) Enqueue(ctx context.Context, q *Queue, payload T, opts Options) (*models.Job, error) {
+	return q.Enqueue(ctx, k.Name, payload, opts)
+}
+
+// Handle registers fn as the handler of this kind. Payloads that cannot be
+// decoded fail the job without retrying.
+func (k Kind[T]) Handle(r *Registry, fn func(ctx context.Context, payload T, job *models.Job) error) {
+	r.Register(k.Name, func(ctx context.Context, job *models.Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+		return fn(ctx, payload, job)
+	})
+}
+
+// permanentError marks an error that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the job fails immediately instead of being
+// retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// isPermanent reports whether err was wrapped with Permanent
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// maintenanceInterval is how often stale jobs are rescued and finished ones
+// purged
+const maintenanceInterval = time.Minute
+
+// abandonTimeout is how long jobs still have after being cancelled on
+// shutdown before the worker stops waiting for them
+const abandonTimeout = 5 * time.Second
+
+// Worker claims and runs jobs of the kinds in its registry
+type Worker struct {
+	repo     *models.JobRepository
+	registry *Registry
+	cfg      config.JobsConfig
+	id       string
+	done     chan struct{}
+}
+
+// NewWorker creates a worker that runs up to cfg.Concurrency jobs at once
+func NewWorker(repo *models.JobRepository, registry *Registry, cfg config.JobsConfig) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		repo:     repo,
+		registry: registry,
+		cfg:      cfg,
+		id:       fmt.Sprintf("%s-%d", host, os.Getpid()),
+		done:     make(chan struct{}, cfg.Concurrency),
+	}
+}
+
+// Run runs jobs until ctx is cancelled. It then stops claiming jobs and
+// gives running ones ShutdownTimeout to finish before cancelling them;
+// cancelled jobs go back to the queue without losing an attempt.
+func (w *Worker) Run(ctx context.Context) {
+	// Jobs outlive ctx so that they can finish during shutdown
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	maintenance := time.NewTicker(maintenanceInterval)
+	defer maintenance.Stop()
+
+	running := 0
+	w.maintain()
+	for {
+		running += w.claim(ctx, jobCtx, w.cfg.Concurrency-running)
+
+		select {
+		case <-ctx.Done():
+			w.shutdown(running, cancelJobs)
+			return
+		case <-ticker.C:
+		case <-w.done:
+			running--
+		case <-maintenance.C:
+			w.maintain()
+		}
+	}
+}
+
+// shutdown waits for running jobs, cancelling them if they take longer than
+// ShutdownTimeout
+func (w *Worker) shutdown(running int, cancelJobs context.CancelFunc) {
+	if running == 0 {
+		return
+	}
+	logrus.WithField("running", running).Info("Waiting for running jobs to finish")
+
+	timeout := time.After(w.cfg.ShutdownTimeout)
+	var abandon <-chan time.Time
+	for running > 0 {
+		select {
+		case <-w.done:
+			running--
+		case <-timeout:
+			logrus.WithField("running", running).Warn("Cancelling running jobs")
+			cancelJobs()
+			abandon = time.After(abandonTimeout)
+		case <-abandon:
+			// Their claims expire and they are rescued later
+			logrus.WithField("running", running).Warn("Abandoning running jobs")
+			return
+		}
+	}
+}
+
+// claim starts up to limit due jobs and returns how many it started
+func (w *Worker) claim(ctx, jobCtx context.Context, limit int) int {
+	kinds := w.registry.Kinds()
+	if limit <= 0 || len(kinds) == 0 || ctx.Err() != nil {
+		return 0
+	}
+
+	claimed, err := w.repo.Claim(ctx, w.id, kinds, limit)
+	if err != nil {
+		if ctx.Err() == nil {
+			logrus.WithError(err).Error("Failed to claim jobs")
+		}
+		return 0
+	}
+
+	for i := range claimed {
+		go func(job *models.Job) {
+			defer func() { w.done <- struct{}{} }()
+			w.process(jobCtx, job)
+		}(&claimed[i])
+	}
+	return len(claimed)
+}
+
+// process runs a claimed job and records the outcome
+func (w *Worker) process(jobCtx context.Context, job *models.Job) {
+	log := logrus.WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"kind":    job.Kind,
+		"attempt": job.Attempts,
+	})
+
+	err := w.run(jobCtx, job)
+	switch {
+	case err == nil:
+		err = w.repo.Complete(job)
+	case jobCtx.Err() != nil:
+		log.WithError(err).Info("Job interrupted by shutdown")
+		err = w.repo.Release(job)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.WithError(err).Warn("Job failed")
+		err = w.repo.Fail(job, err.Error())
+	default:
+		log.WithError(err).Info("Job failed; will retry")
+		err = w.repo.Retry(job, err.Error(), w.retryDelay(job.Attempts))
+	}
+	if err == models.ErrLostLock {
+		// The job was rescued while it ran; whoever holds it now decides
+		log.Warn("Job outcome discarded, the job is no longer claimed by this worker")
+	} else if err != nil {
+		log.WithError(err).Error("Failed to record job outcome")
+	}
+}
+
+// run calls the job's handler with the job timeout, turning panics into
+// errors
+func (w *Worker) run(jobCtx context.Context, job *models.Job) (err error) {
+	fn, ok := w.registry.handler(job.Kind)
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+
+	ctx, cancel := context.WithTimeout(jobCtx, w.cfg.JobTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithField("stack", string(debug.Stack())).Error("Job panicked")
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, job)
+}
+
+// maintain returns the jobs of dead workers to the queue and purges old
+// finished jobs
+func (w *Worker) maintain() {
+	// A claim outlives any attempt, including its grace period on shutdown
+	lease := w.cfg.JobTimeout + w.cfg.ShutdownTimeout + time.Minute
+	if rescued, err := w.repo.RescueStale(lease); err != nil {
+		logrus.WithError(err).Error("Failed to rescue stale jobs")
+	} else if rescued > 0 {
+		logrus.WithField("count", rescued).Warn("Rescued jobs of unresponsive workers")
+	}
+
+	if purged, err := w.repo.PurgeFinished(w.cfg.Retention); err != nil {
+		logrus.WithError(err).Error("Failed to purge finished jobs")
+	} else if purged > 0 {
+		logrus.WithField("count", purged).Info("Purged finished jobs")
+	}
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts: RetryBase, doubling with each further failure up to RetryMax
+func (w *Worker) retryDelay(failures int) time.Duration {
+	delay := w.cfg.RetryBase
+	for i := 1; i < failures && delay < w.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > w.cfg.RetryMax {
+		delay = w.cfg.RetryMax
+	}
+	return delay
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTP sends messages through an SMTP server, using STARTTLS when the server
+// offers it
+type SMTP struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	envelope string // bare address of from, used for MAIL FROM
+}
+
+// NewSMTP creates a mailer for the server at addr (host:port). Username may
+// be empty for servers that do not require authentication.
+func NewSMTP(addr, username, password, from string) (*SMTP, error) {
+	if addr == "" || from == "" {
+		return nil, errors.New("smtp address and sender are required")
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTP{addr: addr, auth: auth, from: from, envelope: sender.Address}, nil
+}
+
+// Send delivers msg
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := format(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, so give up waiting when ctx ends
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.envelope, []string{msg.To}, data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Spool writes each message as an .eml file into a directory instead of
+// sending it, for development and tests
+type Spool struct {
+	dir  string
+	from string
+}
+
+// NewSpool creates a mailer that writes messages into dir
+func NewSpool(dir, from string) (*Spool, error) {
+	if dir == "" {
+		return nil, errors.New("mail spool directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Spool{dir: dir, from: from}, nil
+}
+
+// Send writes msg to a new file in the spool directory
+func (s *Spool) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(s.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0o640)
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// AccessTokenPrefix starts every personal access token so that leaked tokens
+// are easy to recognize and tell apart from JWTs
+const AccessTokenPrefix = "tmpat_"
+
+// accessTokenTouchInterval limits how often last-use tracking writes
+const accessTokenTouchInterval = time.Minute
+
+// ErrInvalidAccessToken is returned for unknown, revoked or expired personal
+// access tokens
+var ErrInvalidAccessToken = errors.New("invalid or expired access token")
+
+// PersonalAccessToken is a long-lived token a user created for scripts
+type PersonalAccessToken struct {
+	ID          int            `db:"id" json:"id"`
+	UserID      int            `db:"user_id" json:"user_id"`
+	Name        string         `db:"name" json:"name"`
+	TokenHash   string         `db:"token_hash" json:"-"`
+	TokenPrefix string         `db:"token_prefix" json:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt   *time.Time     `db:"expires_at" json:"expires_at"`
+	LastUsedAt  *time.Time     `db:"last_used_at" json:"last_used_at"`
+	LastUsedIP  *string        `db:"last_used_ip" json:"last_used_ip"`
+	RevokedAt   *time.Time     `db:"revoked_at" json:"-"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token
+// rather than a JWT
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// AccessTokenRepository handles personal access tokens
+type AccessTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccessTokenRepository creates a new personal access token repository
+func NewAccessTokenRepository(db *sqlx.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+// Create stores a new token and returns its plaintext, which cannot be
+// recovered later
+func (r *AccessTokenRepository) Create(token *PersonalAccessToken) (string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	plaintext := AccessTokenPrefix + secret
+
+	token.TokenHash = hashToken(plaintext)
+	token.TokenPrefix = plaintext[:len(AccessTokenPrefix)+6]
+	err = r.db.QueryRowx(`
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at
+	`, token.UserID, token.Name, token.TokenHash, token.TokenPrefix, token.Scopes, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// ListByUser returns a user's tokens that have not been revoked, newest first
+func (r *AccessTokenRepository) ListByUser(userID int) ([]PersonalAccessToken, error) {
+	tokens := []PersonalAccessToken{}
+	err := r.db.Select(&tokens, `
+		SELECT * FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	return tokens, err
+}
+
+// Revoke stops one of a user's tokens from working. It returns
+// sql.ErrNoRows if the user has no such active token.
+func (r *AccessTokenRepository) Revoke(id, userID int) error {
+	result, err := r.db.Exec(
+		"UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Authenticate looks up a token presented from ip and records its use. It
+// fails with ErrInvalidAccessToken unless the token is active.
+func (r *AccessTokenRepository) Authenticate(plaintext, ip string) (*PersonalAccessToken, error) {
+	token := &PersonalAccessToken{}
+	err := r.db.Get(token, `
+		SELECT * FROM personal_access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+	`, hashToken(plaintext), time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens used by busy scripts are only written once per interval
+	now := time.Now().UTC()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenTouchInterval {
+		_, err := r.db.Exec(
+			"UPDATE personal_access_tokens SET last_used_at = $1, last_used_ip = $2 WHERE id = $3",
+			now, ip, token.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		token.LastUsedAt, token.LastUsedIP = &now, &ip
+	}
+
+	return token, nil
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidAssignee is returned when an assignee does not exist or cannot
+// see the task
+var ErrInvalidAssignee = errors.New("assignee must be an existing user with access to the task")
+
+// Assignment history actions
+const (
+	AssignmentAdded   = "assigned"
+	AssignmentRemoved = "unassigned"
+)
+
+// TaskAssignee is a user assigned to a task
+type TaskAssignee struct {
+	TaskID     int       `db:"task_id" json:"-"`
+	UserID     int       `db:"user_id" json:"user_id"`
+	Username   string    `db:"username" json:"username"`
+	AssignedBy *int      `db:"assigned_by" json:"assigned_by"`
+	AssignedAt time.Time `db:"assigned_at" json:"assigned_at"`
+}
+
+// AssignmentChange is one entry in a task's assignment history
+type AssignmentChange struct {
+	ID        int       `db:"id" json:"id"`
+	TaskID    int       `db:"task_id" json:"task_id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Action    string    `db:"action" json:"action"`
+	ChangedBy *int      `db:"changed_by" json:"changed_by"`
+	ChangedAt time.Time `db:"changed_at" json:"changed_at"`
+}
+
+// AssignmentRepository handles task assignees and watchers
+type AssignmentRepository struct {
+	db *sqlx.DB
+}
+
+// NewAssignmentRepository creates a new assignment repository
+func NewAssignmentRepository(db *sqlx.DB) *AssignmentRepository {
+	return &AssignmentRepository{db: db}
+}
+
+// Assignees returns the users assigned to a task
+func (r *AssignmentRepository) Assignees(taskID int) ([]TaskAssignee, error) {
+	assignees := []TaskAssignee{}
+	err := r.db.Select(&assignees, `
+		SELECT a.task_id, a.user_id, u.username, a.assigned_by, a.assigned_at
+		FROM task_assignees a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.task_id = $1
+		ORDER BY a.assigned_at, a.user_id
+	`, taskID)
+	return assignees, err
+}
+
+// IsAssignee reports whether userID is assigned to taskID
+func (r *AssignmentRepository) IsAssignee(taskID, userID int) (bool, error) {
+	var assigned bool
+	err := r.db.Get(&assigned,
+		"SELECT EXISTS (SELECT 1 FROM task_assignees WHERE task_id = $1 AND user_id = $2)",
+		taskID, userID,
+	)
+	return assigned, err
+}
+
+// SetAssignees replaces the assignees of a task and records who was added
+// and removed. Assignees of a project task must be members of the project.
+func (r *AssignmentRepository) SetAssignees(task *Task, userIDs []int, actorID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setAssignees(tx, task, userIDs, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setAssignees is SetAssignees inside tx
+func setAssignees(tx *sqlx.Tx, task *Task, userIDs []int, actorID int) error {
+	userIDs = uniqueInts(userIDs)
+
+	if len(userIDs) > 0 {
+		var valid int
+		err := tx.Get(&valid, `
+			SELECT COUNT(*) FROM users u
+			WHERE u.id = ANY($1)
+			AND ($2::int IS NULL OR EXISTS (
+				SELECT 1 FROM project_members m WHERE m.project_id = $2 AND m.user_id = u.id
+			))
+		`, pq.Array(userIDs), task.ProjectID)
+		if err != nil {
+			return err
+		}
+		if valid != len(userIDs) {
+			return ErrInvalidAssignee
+		}
+	}
+
+	var removed []int
+	err := tx.Select(&removed, `
+		DELETE FROM task_assignees
+		WHERE task_id = $1 AND NOT (user_id = ANY($2))
+		RETURNING user_id
+	`, task.ID, pq.Array(userIDs))
+	if err != nil {
+		return err
+	}
+
+	var added []int
+	if len(userIDs) > 0 {
+		err = tx.Select(&added, `
+			INSERT INTO task_assignees (task_id, user_id, assigned_by, assigned_at)
+			SELECT $1, unnest($2::int[]), $3, NOW()
+			ON CONFLICT DO NOTHING
+			RETURNING user_id
+		`, task.ID, pq.Array(userIDs), actorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	changes := []struct {
+		action string
+		users  []int
+	}{
+		{AssignmentRemoved, removed},
+		{AssignmentAdded, added},
+	}
+	for _, change := range changes {
+		if len(change.users) == 0 {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO task_assignment_history (task_id, user_id, action, changed_by, changed_at)
+			SELECT $1, unnest($2::int[]), $3, $4, NOW()
+		`, task.ID, pq.Array(change.users), change.action, actorID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History returns a task's assignment changes, oldest first
+func (r *AssignmentRepository) History(taskID int) ([]AssignmentChange, error) {
+	history := []AssignmentChange{}
+	err := r.db.Select(&history,
+		"SELECT * FROM task_assignment_history WHERE task_id = $1 ORDER BY changed_at, id",
+		taskID,
+	)
+	return history, err
+}
+
+// AttachAssignees loads the assignee IDs of each task into its Assignees field
+func (r *AssignmentRepository) AttachAssignees(tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+		tasks[i].Assignees = []int{}
+	}
+
+	var rows []TaskAssignee
+	err := r.db.Select(&rows, `
+		SELECT a.task_id, a.user_id, u.username, a.assigned_by, a.assigned_at
+		FROM task_assignees a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.task_id = ANY($1)
+		ORDER BY a.assigned_at, a.user_id
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byTask := make(map[int][]int, len(tasks))
+	for _, row := range rows {
+		byTask[row.TaskID] = append(byTask[row.TaskID], row.UserID)
+	}
+	for i := range tasks {
+		if assignees, ok := byTask[tasks[i].ID]; ok {
+			tasks[i].Assignees = assignees
+		}
+	}
+
+	return nil
+}
+
+// Watch subscribes a user to a task
+func (r *AssignmentRepository) Watch(taskID, userID int) error {
+	_, err := r.db.Exec(
+		"INSERT INTO task_watchers (task_id, user_id, created_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING",
+		taskID, userID,
+	)
+	return err
+}
+
+// Unwatch unsubscribes a user from a task
+func (r *AssignmentRepository) Unwatch(taskID, userID int) error {
+	_, err := r.db.Exec("DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2", taskID, userID)
+	return err
+}
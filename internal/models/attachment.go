@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Attachment is a file uploaded to a task
+type Attachment struct {
+	ID          int       `db:"id" json:"id"`
+	TaskID      int       `db:"task_id" json:"task_id"`
+	Filename    string    `db:"filename" json:"filename"`
+	SHA256      string    `db:"sha256" json:"sha256"`
+	Size        int64     `db:"size" json:"size"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	UploadedBy  *int      `db:"uploaded_by" json:"uploaded_by"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// StorageKey returns the key under which the attachment's contents are stored
+func (a *Attachment) StorageKey() string {
+	return BlobKey(a.SHA256)
+}
+
+// BlobKey returns the storage key for contents with the given SHA-256 hash
+func BlobKey(sha256 string) string {
+	return "blobs/" + sha256[:2] + "/" + sha256
+}
+
+// blobSweepLockID is the advisory lock serializing orphan sweeps with uploads
+const blobSweepLockID = 72321011
+
+// attachmentColumns selects an attachment joined with its blob
+const attachmentColumns = `
+	a.id, a.task_id, a.filename, a.sha256, b.size, b.content_type, a.uploaded_by, a.created_at
+	FROM task_attachments a
+	JOIN attachment_blobs b ON b.sha256 = a.sha256`
+
+// AttachmentRepository handles attachment metadata
+type AttachmentRepository struct {
+	db *sqlx.DB
+}
+
+// NewAttachmentRepository creates a new attachment repository
+func NewAttachmentRepository(db *sqlx.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// BlobExists reports whether contents with the given hash are already stored
+func (r *AttachmentRepository) BlobExists(sha256 string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS (SELECT 1 FROM attachment_blobs WHERE sha256 = $1)", sha256)
+	return exists, err
+}
+
+// Create records an attachment, registering its blob if it is new. It
+// reports whether the blob row was created by this call, in which case any
+// contents stored earlier may have been removed as orphaned in the meantime.
+func (r *AttachmentRepository) Create(attachment *Attachment) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Wait for a running orphan sweep so it cannot remove this blob's contents
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock_shared($1)", blobSweepLockID); err != nil {
+		return false, err
+	}
+
+	var inserted bool
+	err = tx.Get(&inserted, `
+		INSERT INTO attachment_blobs (sha256, size, content_type, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (sha256) DO UPDATE SET sha256 = EXCLUDED.sha256
+		RETURNING xmax = 0
+	`, attachment.SHA256, attachment.Size, attachment.ContentType)
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowx(`
+		INSERT INTO task_attachments (task_id, filename, sha256, uploaded_by, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at
+	`, attachment.TaskID, attachment.Filename, attachment.SHA256, attachment.UploadedBy).
+		Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, tx.Commit()
+}
+
+// FindByID finds an attachment by ID
+func (r *AttachmentRepository) FindByID(id int) (*Attachment, error) {
+	attachment := &Attachment{}
+	err := r.db.Get(attachment, "SELECT "+attachmentColumns+" WHERE a.id = $1", id)
+	return attachment, err
+}
+
+// ListByTask returns the attachments of a task, oldest first
+func (r *AttachmentRepository) ListByTask(taskID int) ([]Attachment, error) {
+	attachments := []Attachment{}
+	err := r.db.Select(&attachments,
+		"SELECT "+attachmentColumns+" WHERE a.task_id = $1 ORDER BY a.created_at, a.id",
+		taskID,
+	)
+	return attachments, err
+}
+
+// Delete removes an attachment's metadata row
+func (r *AttachmentRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM task_attachments WHERE id = $1", id)
+	return err
+}
+
+// DeleteOrphanedBlobs forgets blobs that no attachment refers to any more,
+// for example after a task or project delete cascaded to its attachments,
+// calling remove for each so the stored contents can be deleted too
+func (r *AttachmentRepository) DeleteOrphanedBlobs(remove func(sha256 string) error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep uploads from reusing a blob while its contents are being removed
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", blobSweepLockID); err != nil {
+		return err
+	}
+
+	var hashes []string
+	err = tx.Select(&hashes, `
+		DELETE FROM attachment_blobs b
+		WHERE NOT EXISTS (SELECT 1 FROM task_attachments a WHERE a.sha256 = b.sha256)
+		RETURNING b.sha256
+	`)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if err := remove(hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
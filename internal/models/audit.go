@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Audit actions recorded by handlers in addition to the per-request audit
+const (
+	AuditLoginFailed        = "login_failed"
+	AuditAccountLocked      = "account_locked"
+	AuditIPLocked           = "ip_locked"
+	AuditAccountUnlocked    = "account_unlocked"
+	AuditPasswordChanged    = "password_changed"
+	AuditAccountDeactivated = "account_deactivated"
+	AuditAccountReactivated = "account_reactivated"
+	AuditAccountDeleted     = "account_deleted"
+)
+
+// AuditEntry is one row of audit_logs
+type AuditEntry struct {
+	UserID     *int
+	Action     string
+	EntityType string
+	EntityID   *int
+	Details    map[string]interface{}
+	IPAddress  string
+}
+
+// AuditRepository writes audit log entries
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record inserts an audit log entry
+func (r *AuditRepository) Record(entry AuditEntry) error {
+	var details []byte
+	if entry.Details != nil {
+		var err error
+		if details, err = json.Marshal(entry.Details); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO audit_logs (user_id, action, entity_type, entity_id, details, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, entry.UserID, entry.Action, entry.EntityType, entry.EntityID, details, entry.IPAddress)
+	return err
+}
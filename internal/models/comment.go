@@ -0,0 +1,256 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// mentionPattern matches @username mentions in a comment body, ignoring
+// email addresses and trailing punctuation
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w(?:[\w.-]*\w)?)`)
+
+// Comment is a markdown message in a task's discussion thread
+type Comment struct {
+	ID        int        `db:"id" json:"id"`
+	TaskID    int        `db:"task_id" json:"task_id"`
+	UserID    *int       `db:"user_id" json:"user_id"`
+	Body      string     `db:"body" json:"body"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	EditedAt  *time.Time `db:"edited_at" json:"edited_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Mentions  []int      `db:"-" json:"mentions"`
+}
+
+// CommentRevision is an earlier body of an edited comment
+type CommentRevision struct {
+	ID        int       `db:"id" json:"id"`
+	CommentID int       `db:"comment_id" json:"comment_id"`
+	Body      string    `db:"body" json:"body"`
+	EditedBy  *int      `db:"edited_by" json:"edited_by"`
+	EditedAt  time.Time `db:"edited_at" json:"edited_at"`
+}
+
+// ParseMentions returns the distinct usernames mentioned in body
+func ParseMentions(body string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := match[1]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CommentRepository handles task comments and their mentions
+type CommentRepository struct {
+	db *sqlx.DB
+}
+
+// NewCommentRepository creates a new comment repository
+func NewCommentRepository(db *sqlx.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create adds a comment and records the users it mentions
+func (r *CommentRepository) Create(comment *Comment) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowx(`
+		INSERT INTO task_comments (task_id, user_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, comment.TaskID, comment.UserID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := saveMentions(tx, comment); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindByID finds a comment by ID
+func (r *CommentRepository) FindByID(id int) (*Comment, error) {
+	comment := &Comment{}
+	err := r.db.Get(comment, "SELECT * FROM task_comments WHERE id = $1", id)
+	if err != nil {
+		return comment, err
+	}
+
+	comments := []Comment{*comment}
+	if err := r.attachMentions(comments); err != nil {
+		return comment, err
+	}
+	return &comments[0], nil
+}
+
+// ListByTask returns a task's comments, oldest first. Deleted comments keep
+// their place in the thread but lose their body.
+func (r *CommentRepository) ListByTask(taskID int) ([]Comment, error) {
+	comments := []Comment{}
+	err := r.db.Select(&comments, `
+		SELECT id, task_id, user_id,
+			CASE WHEN deleted_at IS NULL THEN body ELSE '' END AS body,
+			created_at, updated_at, edited_at, deleted_at
+		FROM task_comments
+		WHERE task_id = $1
+		ORDER BY created_at, id
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachMentions(comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ListMentioning returns live comments that mention userID on tasks the user
+// can still see, newest first. readAny includes every task.
+func (r *CommentRepository) ListMentioning(userID int, readAny bool) ([]Comment, error) {
+	comments := []Comment{}
+	err := r.db.Select(&comments, `
+		SELECT c.* FROM task_comments c
+		JOIN comment_mentions m ON m.comment_id = c.id
+		JOIN tasks t ON t.id = c.task_id
+		WHERE m.user_id = $1 AND c.deleted_at IS NULL
+		AND ($2 OR (t.project_id IS NULL AND t.user_id = $1)
+			OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $1)
+			OR t.id IN (SELECT task_id FROM task_assignees WHERE user_id = $1))
+		ORDER BY c.created_at DESC, c.id DESC
+	`, userID, readAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachMentions(comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Update replaces a comment's body, keeping the previous body as a revision
+func (r *CommentRepository) Update(comment *Comment, editorID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO task_comment_revisions (comment_id, body, edited_by, edited_at)
+		SELECT id, body, $2, NOW() FROM task_comments WHERE id = $1
+	`, comment.ID, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowx(`
+		UPDATE task_comments SET body = $1, edited_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+		RETURNING edited_at, updated_at
+	`, comment.Body, comment.ID).Scan(&comment.EditedAt, &comment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM comment_mentions WHERE comment_id = $1", comment.ID); err != nil {
+		return err
+	}
+	if err := saveMentions(tx, comment); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete soft-deletes a comment; its revisions are kept for auditing
+func (r *CommentRepository) Delete(id int) error {
+	_, err := r.db.Exec(
+		"UPDATE task_comments SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	)
+	return err
+}
+
+// Revisions returns the earlier bodies of a comment, oldest first
+func (r *CommentRepository) Revisions(commentID int) ([]CommentRevision, error) {
+	revisions := []CommentRevision{}
+	err := r.db.Select(&revisions,
+		"SELECT * FROM task_comment_revisions WHERE comment_id = $1 ORDER BY edited_at, id",
+		commentID,
+	)
+	return revisions, err
+}
+
+// saveMentions records comment.Mentions for a comment
+func saveMentions(tx *sqlx.Tx, comment *Comment) error {
+	comment.Mentions = uniqueInts(comment.Mentions)
+	if len(comment.Mentions) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(
+		"INSERT INTO comment_mentions (comment_id, user_id) SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING",
+		comment.ID, pq.Array(comment.Mentions),
+	)
+	return err
+}
+
+// commentMention is a mention row joined by comment
+type commentMention struct {
+	CommentID int `db:"comment_id"`
+	UserID    int `db:"user_id"`
+}
+
+// attachMentions loads the mentioned user IDs of each comment
+func (r *CommentRepository) attachMentions(comments []Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+		comments[i].Mentions = []int{}
+	}
+
+	var rows []commentMention
+	err := r.db.Select(&rows,
+		"SELECT comment_id, user_id FROM comment_mentions WHERE comment_id = ANY($1) ORDER BY user_id",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+
+	byComment := make(map[int][]int, len(comments))
+	for _, row := range rows {
+		byComment[row.CommentID] = append(byComment[row.CommentID], row.UserID)
+	}
+	for i := range comments {
+		// Deleted comments no longer reveal who they mentioned
+		if comments[i].DeletedAt != nil {
+			continue
+		}
+		if mentions, ok := byComment[comments[i].ID]; ok {
+			comments[i].Mentions = mentions
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDependencyCycle is returned when a new edge would create a cycle
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+// ErrHierarchyCycle is returned when a task would become its own ancestor
+var ErrHierarchyCycle = errors.New("task cannot be nested under itself or its subtasks")
+
+// priorityRank orders tasks from most to least urgent in SQL
+const priorityRank = `CASE priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'normal' THEN 2 ELSE 3 END`
+
+// Dependency is an edge meaning TaskID is blocked by DependsOnID
+type Dependency struct {
+	TaskID      int  `db:"task_id" json:"task_id"`
+	DependsOnID int  `db:"depends_on_id" json:"depends_on_id"`
+	CreatedBy   *int `db:"created_by" json:"created_by"`
+}
+
+// DependencyRepository handles task hierarchy and dependency edges
+type DependencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewDependencyRepository creates a new dependency repository
+func NewDependencyRepository(db *sqlx.DB) *DependencyRepository {
+	return &DependencyRepository{db: db}
+}
+
+// Children returns the direct subtasks of a task that match visible
+func (r *DependencyRepository) Children(taskID int, visible TaskFilter) ([]Task, error) {
+	where := &whereBuilder{}
+	where.add("parent_id = ?", taskID)
+	return r.related(where, visible, "created_at, id")
+}
+
+// CheckParent returns ErrHierarchyCycle if making parentID the parent of
+// taskID would nest a task under itself
+func (r *DependencyRepository) CheckParent(taskID, parentID int) error {
+	if taskID == parentID {
+		return ErrHierarchyCycle
+	}
+
+	var cycle bool
+	err := r.db.Get(&cycle, `
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT parent_id FROM tasks WHERE id = $1
+			UNION
+			SELECT t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)
+	`, parentID, taskID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrHierarchyCycle
+	}
+	return nil
+}
+
+// Blockers returns the tasks that taskID depends on that match visible
+func (r *DependencyRepository) Blockers(taskID int, visible TaskFilter) ([]Task, error) {
+	where := &whereBuilder{}
+	where.add("id IN (SELECT depends_on_id FROM task_dependencies WHERE task_id = ?)", taskID)
+	return r.related(where, visible, "id")
+}
+
+// Dependents returns the tasks that are blocked by taskID that match visible
+func (r *DependencyRepository) Dependents(taskID int, visible TaskFilter) ([]Task, error) {
+	where := &whereBuilder{}
+	where.add("id IN (SELECT task_id FROM task_dependencies WHERE depends_on_id = ?)", taskID)
+	return r.related(where, visible, "id")
+}
+
+// related returns the tasks matching where and the conditions of visible,
+// sorted by orderBy. Callers pass the visibility filter of the requesting
+// user so that linked tasks they cannot see stay hidden.
+func (r *DependencyRepository) related(where *whereBuilder, visible TaskFilter, orderBy string) ([]Task, error) {
+	applyTaskFilter(where, &visible)
+	tasks := []Task{}
+	err := r.db.Select(&tasks, "SELECT "+taskSelect+" FROM tasks"+where.clause()+" ORDER BY "+orderBy, where.args...)
+	return tasks, err
+}
+
+// OpenBlockerIDs returns the IDs of blockers of taskID that are not closed
+func (r *DependencyRepository) OpenBlockerIDs(taskID int) ([]int, error) {
+	var ids []int
+	err := r.db.Select(&ids, `
+		SELECT t.id FROM tasks t
+		JOIN task_dependencies d ON d.depends_on_id = t.id
+		WHERE d.task_id = $1 AND t.status NOT IN ($2, $3)
+		ORDER BY t.id
+	`, taskID, StatusDone, StatusCancelled)
+	return ids, err
+}
+
+// Add records that dep.TaskID is blocked by dep.DependsOnID, rejecting edges
+// that would create a cycle
+func (r *DependencyRepository) Add(dep *Dependency) error {
+	if dep.TaskID == dep.DependsOnID {
+		return ErrDependencyCycle
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialize writers so two concurrent inserts cannot close a cycle together
+	if _, err := tx.Exec("LOCK TABLE task_dependencies IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	// A cycle exists if TaskID is already reachable from DependsOnID
+	var cycle bool
+	err = tx.Get(&cycle, `
+		WITH RECURSIVE reachable(id) AS (
+			SELECT depends_on_id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.depends_on_id FROM task_dependencies d JOIN reachable r ON d.task_id = r.id
+		)
+		SELECT EXISTS (SELECT 1 FROM reachable WHERE id = $2)
+	`, dep.DependsOnID, dep.TaskID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO task_dependencies (task_id, depends_on_id, created_by, created_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT DO NOTHING`,
+		dep.TaskID, dep.DependsOnID, dep.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Remove deletes a dependency edge
+func (r *DependencyRepository) Remove(taskID, dependsOnID int) error {
+	_, err := r.db.Exec("DELETE FROM task_dependencies WHERE task_id = $1 AND depends_on_id = $2", taskID, dependsOnID)
+	return err
+}
+
+// WorkQueue returns a user's open tasks in dependency order. Ready tasks
+// have no open blockers; the queue lists every open task so that each one
+// appears after all of its blockers, most urgent and soonest due first.
+func (r *DependencyRepository) WorkQueue(userID int) (ready []Task, queue []Task, err error) {
+	var tasks []Task
+	err = r.db.Select(&tasks, `
+		SELECT `+taskSelect+` FROM tasks
+		WHERE user_id = $1 AND status NOT IN ($2, $3)
+		ORDER BY `+priorityRank+`, COALESCE(due_date, 'infinity'::timestamp), id
+	`, userID, StatusDone, StatusCancelled)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Open edges whose dependent task belongs to the user
+	var edges []Dependency
+	err = r.db.Select(&edges, `
+		SELECT d.task_id, d.depends_on_id, d.created_by
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		JOIN tasks b ON b.id = d.depends_on_id
+		WHERE t.user_id = $1 AND t.status NOT IN ($2, $3) AND b.status NOT IN ($2, $3)
+	`, userID, StatusDone, StatusCancelled)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ready, queue = orderByDependencies(tasks, edges)
+	return ready, queue, nil
+}
+
+// orderByDependencies runs Kahn's algorithm over tasks, which must already be
+// sorted by preference. Blockers outside tasks keep their dependents out of
+// the ready list but cannot be scheduled here.
+func orderByDependencies(tasks []Task, edges []Dependency) (ready []Task, queue []Task) {
+	position := make(map[int]int, len(tasks))
+	for i, task := range tasks {
+		position[task.ID] = i
+	}
+
+	indegree := make(map[int]int, len(tasks))
+	external := make(map[int]bool)
+	dependents := make(map[int][]int)
+	for _, edge := range edges {
+		if _, ok := position[edge.DependsOnID]; !ok {
+			external[edge.TaskID] = true
+			continue
+		}
+		indegree[edge.TaskID]++
+		dependents[edge.DependsOnID] = append(dependents[edge.DependsOnID], edge.TaskID)
+	}
+
+	var available []int
+	for _, task := range tasks {
+		if indegree[task.ID] == 0 && !external[task.ID] {
+			ready = append(ready, task)
+		}
+		if indegree[task.ID] == 0 {
+			available = append(available, position[task.ID])
+		}
+	}
+
+	queue = make([]Task, 0, len(tasks))
+	for len(available) > 0 {
+		sort.Ints(available)
+		next := available[0]
+		available = available[1:]
+		queue = append(queue, tasks[next])
+
+		for _, id := range dependents[tasks[next].ID] {
+			indegree[id]--
+			if indegree[id] == 0 {
+				available = append(available, position[id])
+			}
+		}
+	}
+
+	if ready == nil {
+		ready = []Task{}
+	}
+	return ready, queue
+}
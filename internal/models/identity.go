@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvalidLoginState is returned for unknown, used or expired OIDC states
+var ErrInvalidLoginState = errors.New("invalid or expired login state")
+
+// Identity links a local user to an account at an identity provider
+type Identity struct {
+	ID          int       `db:"id" json:"id"`
+	UserID      int       `db:"user_id" json:"user_id"`
+	Provider    string    `db:"provider" json:"provider"`
+	Subject     string    `db:"subject" json:"subject"`
+	Email       string    `db:"email" json:"email"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	LastLoginAt time.Time `db:"last_login_at" json:"last_login_at"`
+}
+
+// LoginState is an authorization request waiting for the provider callback
+type LoginState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// IdentityRepository handles linked identities and OIDC login states
+type IdentityRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *sqlx.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Find returns the identity for a provider subject, or nil if it is not linked
+func (r *IdentityRepository) Find(provider, subject string) (*Identity, error) {
+	identity := &Identity{}
+	err := r.db.Get(identity, "SELECT * FROM user_identities WHERE provider = $1 AND subject = $2", provider, subject)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+// ListByUser returns the identities linked to a user
+func (r *IdentityRepository) ListByUser(userID int) ([]Identity, error) {
+	identities := []Identity{}
+	err := r.db.Select(&identities, "SELECT * FROM user_identities WHERE user_id = $1 ORDER BY provider", userID)
+	return identities, err
+}
+
+// Link connects a provider subject to a user
+func (r *IdentityRepository) Link(identity *Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id, created_at, last_login_at
+	`
+
+	return r.db.QueryRowx(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).
+		Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+}
+
+// Touch records a login through an identity and the email it now reports
+func (r *IdentityRepository) Touch(id int, email string) error {
+	_, err := r.db.Exec("UPDATE user_identities SET last_login_at = NOW(), email = $1 WHERE id = $2", email, id)
+	return err
+}
+
+// Unlink removes one of a user's identities
+func (r *IdentityRepository) Unlink(id, userID int) error {
+	result, err := r.db.Exec("DELETE FROM user_identities WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateState stores the PKCE verifier and nonce of a new authorization
+// request under the hash of state. Expired states are cleared out on the way.
+func (r *IdentityRepository) CreateState(state, provider, verifier, nonce string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := r.db.Exec("DELETE FROM oidc_login_states WHERE expires_at < $1", now); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, hashToken(state), provider, verifier, nonce, now.Add(ttl))
+	return err
+}
+
+// ConsumeState removes and returns the authorization request for state. It
+// fails with ErrInvalidLoginState if the state is unknown, expired, already
+// used or was issued for another provider.
+func (r *IdentityRepository) ConsumeState(state, provider string) (*LoginState, error) {
+	loginState := &LoginState{}
+	err := r.db.Get(loginState, `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > $3
+		RETURNING *
+	`, hashToken(state), provider, time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidLoginState
+	}
+	return loginState, err
+}
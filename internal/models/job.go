@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// ErrDuplicateJob is returned when a job is queued while another with the
+// same unique key is pending or running
+var ErrDuplicateJob = errors.New("a job with this unique key is already queued")
+
+// ErrLostLock is returned when the outcome of a job is recorded after its
+// claim expired and the job was rescued or claimed again
+var ErrLostLock = errors.New("job is no longer claimed by this worker")
+
+// Job is a unit of background work
+type Job struct {
+	ID          int64      `db:"id" json:"id"`
+	Kind        string     `db:"kind" json:"kind"`
+	Payload     JSON       `db:"payload" json:"payload"`
+	Status      string     `db:"status" json:"status"`
+	UniqueKey   *string    `db:"unique_key" json:"unique_key"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	MaxAttempts int        `db:"max_attempts" json:"max_attempts"`
+	RunAt       time.Time  `db:"run_at" json:"run_at"`
+	LockedBy    *string    `db:"locked_by" json:"locked_by"`
+	LockedAt    *time.Time `db:"locked_at" json:"locked_at"`
+	LastError   *string    `db:"last_error" json:"last_error"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	FinishedAt  *time.Time `db:"finished_at" json:"finished_at"`
+}
+
+// JobRepository handles the background job queue
+type JobRepository struct {
+	db *sqlx.DB
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *sqlx.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue adds a pending job. A zero RunAt means now. It returns
+// ErrDuplicateJob if the job's unique key is taken.
+func (r *JobRepository) Enqueue(ctx context.Context, job *Job) error {
+	runAt := &job.RunAt
+	if job.RunAt.IsZero() {
+		runAt = nil
+	}
+
+	err := r.db.GetContext(ctx, job, `
+		INSERT INTO jobs (kind, payload, unique_key, max_attempts, run_at, created_at, updated_at)
+		VALUES ($1, COALESCE($2::jsonb, '{}'), $3, $4, COALESCE($5, NOW()), NOW(), NOW())
+		ON CONFLICT (unique_key) WHERE status IN ('pending', 'running') DO NOTHING
+		RETURNING *
+	`, job.Kind, job.Payload, job.UniqueKey, job.MaxAttempts, runAt)
+	if err == sql.ErrNoRows {
+		return ErrDuplicateJob
+	}
+	return err
+}
+
+// Claim marks up to limit due pending jobs of the given kinds as running on
+// behalf of worker and returns them. Jobs claimed by another worker are
+// skipped rather than waited for.
+func (r *JobRepository) Claim(ctx context.Context, worker string, kinds []string, limit int) ([]Job, error) {
+	jobs := []Job{}
+	err := r.db.SelectContext(ctx, &jobs, `
+		UPDATE jobs
+		SET status = 'running', locked_by = $1, locked_at = NOW(), attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= NOW() AND kind = ANY($2)
+			ORDER BY run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, worker, pq.StringArray(kinds), limit)
+	return jobs, err
+}
+
+// Complete marks a running job as succeeded. It returns ErrLostLock if the
+// claim on the job has been lost.
+func (r *JobRepository) Complete(job *Job) error {
+	return r.finish(job, `
+		UPDATE jobs
+		SET status = 'succeeded', locked_by = NULL, locked_at = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3
+	`)
+}
+
+// Retry returns a failed running job to the queue to run again after delay.
+// It returns ErrLostLock if the claim on the job has been lost.
+func (r *JobRepository) Retry(job *Job, reason string, delay time.Duration) error {
+	return r.finish(job, `
+		UPDATE jobs
+		SET status = 'pending', locked_by = NULL, locked_at = NULL, last_error = $4,
+			run_at = NOW() + make_interval(secs => $5), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3
+	`, reason, delay.Seconds())
+}
+
+// Fail marks a running job as failed for good. It returns ErrLostLock if the
+// claim on the job has been lost.
+func (r *JobRepository) Fail(job *Job, reason string) error {
+	return r.finish(job, `
+		UPDATE jobs
+		SET status = 'failed', locked_by = NULL, locked_at = NULL, last_error = $4, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3
+	`, reason)
+}
+
+// Release returns a running job that was interrupted, not failed, to the
+// queue without counting the attempt. It returns ErrLostLock if the claim
+// on the job has been lost.
+func (r *JobRepository) Release(job *Job) error {
+	return r.finish(job, `
+		UPDATE jobs
+		SET status = 'pending', locked_by = NULL, locked_at = NULL, attempts = attempts - 1, updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3
+	`)
+}
+
+// finish runs an update of a claimed job's outcome. The job is matched by
+// its worker and attempt, which identify the claim: once a claim has
+// expired and the job has been claimed again, even by the same worker, the
+// update matches nothing.
+func (r *JobRepository) finish(job *Job, query string, args ...interface{}) error {
+	args = append([]interface{}{job.ID, job.LockedBy, job.Attempts}, args...)
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLostLock
+	}
+	return nil
+}
+
+// RescueStale returns jobs that have been running for longer than lease,
+// whose worker presumably died, to the queue. Their attempt still counts.
+func (r *JobRepository) RescueStale(lease time.Duration) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'pending' END,
+			finished_at = CASE WHEN attempts >= max_attempts THEN NOW() END,
+			last_error = 'worker stopped responding', locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE status = 'running' AND locked_at < NOW() - make_interval(secs => $1)
+	`, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeFinished deletes succeeded and failed jobs that finished longer than
+// retention ago
+func (r *JobRepository) PurgeFinished(retention time.Duration) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM jobs WHERE finished_at < NOW() - make_interval(secs => $1)", retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
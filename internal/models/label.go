@@ -0,0 +1,174 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrLabelNotVisible is returned when a task references a label its owner cannot use
+var ErrLabelNotVisible = errors.New("label not found")
+
+// ErrLabelExists is returned when creating a label whose name is already
+// taken by another global label or another label of the same user
+var ErrLabelExists = errors.New("a label with this name already exists")
+
+// Label is a tag that can be attached to many tasks. Labels without a
+// UserID are global and can be used by everyone.
+type Label struct {
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name" validate:"required,min=1,max=50"`
+	Color     *string   `db:"color" json:"color,omitempty" validate:"omitempty,hexcolor"`
+	UserID    *int      `db:"user_id" json:"user_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// LabelRepository handles database operations for labels
+type LabelRepository struct {
+	db *sqlx.DB
+}
+
+// NewLabelRepository creates a new label repository
+func NewLabelRepository(db *sqlx.DB) *LabelRepository {
+	return &LabelRepository{db: db}
+}
+
+// Create adds a new label. ErrLabelExists is returned if the name is taken.
+func (r *LabelRepository) Create(label *Label) error {
+	query := `
+		INSERT INTO labels (name, color, user_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowx(query, label.Name, label.Color, label.UserID).Scan(&label.ID, &label.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrLabelExists
+	}
+	return err
+}
+
+// FindByID finds a label by ID
+func (r *LabelRepository) FindByID(id int) (*Label, error) {
+	label := &Label{}
+	err := r.db.Get(label, "SELECT * FROM labels WHERE id = $1", id)
+	return label, err
+}
+
+// ListVisible returns global labels plus the labels owned by userID
+func (r *LabelRepository) ListVisible(userID int) ([]Label, error) {
+	labels := []Label{}
+	err := r.db.Select(&labels, "SELECT * FROM labels WHERE user_id IS NULL OR user_id = $1 ORDER BY name", userID)
+	return labels, err
+}
+
+// Delete removes a label and detaches it from all tasks
+func (r *LabelRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM labels WHERE id = $1", id)
+	return err
+}
+
+// SetTaskLabels replaces the labels attached to a task. Every label must be
+// global or owned by ownerID.
+func (r *LabelRepository) SetTaskLabels(taskID, ownerID int, labelIDs []int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTaskLabels(tx, taskID, ownerID, labelIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setTaskLabels is SetTaskLabels inside tx
+func setTaskLabels(tx *sqlx.Tx, taskID, ownerID int, labelIDs []int) error {
+	if len(labelIDs) > 0 {
+		var visible int
+		err := tx.Get(&visible,
+			"SELECT COUNT(*) FROM labels WHERE id = ANY($1) AND (user_id IS NULL OR user_id = $2)",
+			pq.Array(labelIDs), ownerID,
+		)
+		if err != nil {
+			return err
+		}
+		if visible != len(uniqueInts(labelIDs)) {
+			return ErrLabelNotVisible
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM task_labels WHERE task_id = $1", taskID); err != nil {
+		return err
+	}
+
+	if len(labelIDs) > 0 {
+		_, err := tx.Exec(
+			"INSERT INTO task_labels (task_id, label_id) SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING",
+			taskID, pq.Array(labelIDs),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskLabel is a label joined with the task it is attached to
+type taskLabel struct {
+	TaskID int `db:"task_id"`
+	Label
+}
+
+// AttachLabels loads the labels of each task into its Labels field
+func (r *LabelRepository) AttachLabels(tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+		tasks[i].Labels = []Label{}
+	}
+
+	var rows []taskLabel
+	err := r.db.Select(&rows, `
+		SELECT tl.task_id, l.*
+		FROM task_labels tl
+		JOIN labels l ON l.id = tl.label_id
+		WHERE tl.task_id = ANY($1)
+		ORDER BY l.name
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byTask := make(map[int][]Label, len(tasks))
+	for _, row := range rows {
+		byTask[row.TaskID] = append(byTask[row.TaskID], row.Label)
+	}
+	for i := range tasks {
+		if labels, ok := byTask[tasks[i].ID]; ok {
+			tasks[i].Labels = labels
+		}
+	}
+
+	return nil
+}
+
+// uniqueInts returns values with duplicates removed
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	result := make([]int, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
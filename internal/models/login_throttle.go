@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Scopes failed logins are counted in
+const (
+	ThrottleUsername = "username"
+	ThrottleIP       = "ip"
+)
+
+// ThrottleKey identifies a failure counter
+type ThrottleKey struct {
+	Scope string
+	Key   string
+}
+
+// ThrottlePolicy decides how failed logins slow down further attempts
+type ThrottlePolicy struct {
+	// Failures allowed before backoff starts
+	FreeAttempts int
+	// Delay after the first failure beyond FreeAttempts; doubles each time
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Failures after which the key is locked; 0 disables locking
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Failures older than this are forgotten
+	Window time.Duration
+}
+
+// Backoff returns how long to wait after the given number of failures
+func (p ThrottlePolicy) Backoff(failures int) time.Duration {
+	n := failures - p.FreeAttempts
+	if n <= 0 {
+		return 0
+	}
+
+	delay := p.BackoffBase
+	for i := 1; i < n && delay < p.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > p.BackoffMax {
+		delay = p.BackoffMax
+	}
+	return delay
+}
+
+// LoginThrottleRepository counts failed logins
+type LoginThrottleRepository struct {
+	db *sqlx.DB
+}
+
+// NewLoginThrottleRepository creates a new login throttle repository
+func NewLoginThrottleRepository(db *sqlx.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db}
+}
+
+// BlockedUntil returns the time until which any of keys is locked or backing
+// off, or the zero time if a login may be attempted now
+func (r *LoginThrottleRepository) BlockedUntil(keys []ThrottleKey, now time.Time) (time.Time, error) {
+	var until time.Time
+	for _, key := range keys {
+		var blocked sql.NullTime
+		err := r.db.Get(&blocked, `
+			SELECT GREATEST(next_attempt_at, locked_until) FROM login_failures
+			WHERE scope = $1 AND key = $2
+		`, key.Scope, key.Key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if blocked.Valid && blocked.Time.After(now) && blocked.Time.After(until) {
+			until = blocked.Time
+		}
+	}
+	return until, nil
+}
+
+// RecordFailure counts a failed login against key and applies policy. It
+// returns the failure count and whether this failure locked the key.
+func (r *LoginThrottleRepository) RecordFailure(key ThrottleKey, policy ThrottlePolicy, now time.Time) (int, bool, error) {
+	now = now.UTC()
+
+	var failures int
+	err := r.db.Get(&failures, `
+		INSERT INTO login_failures (scope, key, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failure_at < $4 THEN 1 ELSE login_failures.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures
+	`, key.Scope, key.Key, now, now.Add(-policy.Window))
+	if err != nil {
+		return 0, false, err
+	}
+
+	var nextAttempt, lockedUntil *time.Time
+	if delay := policy.Backoff(failures); delay > 0 {
+		t := now.Add(delay)
+		nextAttempt = &t
+	}
+	// Once over the threshold every further failure locks the key again
+	locked := policy.LockoutThreshold > 0 && failures >= policy.LockoutThreshold
+	if locked {
+		t := now.Add(policy.LockoutDuration)
+		lockedUntil = &t
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE login_failures
+		SET next_attempt_at = $3, locked_until = COALESCE($4, locked_until)
+		WHERE scope = $1 AND key = $2
+	`, key.Scope, key.Key, nextAttempt, lockedUntil)
+	if err != nil {
+		return 0, false, err
+	}
+	return failures, locked, nil
+}
+
+// Reset forgets the failures counted against key, lifting any lock. It
+// reports whether there was anything to forget.
+func (r *LoginThrottleRepository) Reset(key ThrottleKey) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM login_failures WHERE scope = $1 AND key = $2", key.Scope, key.Key)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// PurgeStale deletes counters whose failures are older than window and
+// which are not locked
+func (r *LoginThrottleRepository) PurgeStale(window time.Duration, now time.Time) (int64, error) {
+	now = now.UTC()
+	result, err := r.db.Exec(`
+		DELETE FROM login_failures
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $2)
+	`, now.Add(-window), now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
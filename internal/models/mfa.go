@@ -0,0 +1,349 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes are issued at a time
+	RecoveryCodeCount = 10
+	// MaxChallengeAttempts is how many codes may be tried per MFA challenge
+	MaxChallengeAttempts = 5
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling a user who already uses MFA
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnrolled is returned when a user has not started or finished enrollment
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not set up")
+	// ErrInvalidChallenge is returned for unknown, expired or exhausted MFA challenges
+	ErrInvalidChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+// MFAEnrollment is a user's TOTP secret and whether it is in use
+type MFAEnrollment struct {
+	UserID    int        `db:"user_id"`
+	Secret    string     `db:"secret"`
+	EnabledAt *time.Time `db:"enabled_at"`
+	LastStep  *int64     `db:"last_step"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// MFAStatus summarizes a user's two-factor setup
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	Required               bool       `json:"required"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// MFARepository handles TOTP enrollment, recovery codes, login challenges
+// and the roles that must use MFA
+type MFARepository struct {
+	db *sqlx.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// Enrollment returns a user's enrollment, or ErrMFANotEnrolled if there is none
+func (r *MFARepository) Enrollment(userID int) (*MFAEnrollment, error) {
+	enrollment := &MFAEnrollment{}
+	err := r.db.Get(enrollment, "SELECT * FROM user_mfa WHERE user_id = $1", userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFANotEnrolled
+	}
+	return enrollment, err
+}
+
+// IsEnabled reports whether a user has finished enrolling
+func (r *MFARepository) IsEnabled(userID int) (bool, error) {
+	var enabled bool
+	err := r.db.Get(&enabled,
+		"SELECT EXISTS(SELECT 1 FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL)",
+		userID,
+	)
+	return enabled, err
+}
+
+// Status returns a summary of a user's two-factor setup
+func (r *MFARepository) Status(userID int, role string) (*MFAStatus, error) {
+	status := &MFAStatus{}
+	err := r.db.QueryRow(`
+		SELECT
+			(SELECT enabled_at FROM user_mfa WHERE user_id = $1),
+			EXISTS(SELECT 1 FROM mfa_required_roles WHERE role = $2),
+			(SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL)
+	`, userID, role).Scan(&status.EnabledAt, &status.Required, &status.RecoveryCodesRemaining)
+	status.Enabled = status.EnabledAt != nil
+	return status, err
+}
+
+// EnrollmentRequired reports whether a user's role requires MFA that the user
+// has not set up yet
+func (r *MFARepository) EnrollmentRequired(userID int, role string) (bool, error) {
+	var required bool
+	err := r.db.Get(&required, `
+		SELECT EXISTS(SELECT 1 FROM mfa_required_roles WHERE role = $2)
+			AND NOT EXISTS(SELECT 1 FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL)
+	`, userID, role)
+	return required, err
+}
+
+// Begin stores a new, not yet enabled secret for a user, replacing any
+// unfinished enrollment
+func (r *MFARepository) Begin(userID int, secret string) error {
+	result, err := r.db.Exec(`
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = NULL, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// Enable turns on MFA for a user with a pending enrollment and returns a
+// fresh set of recovery codes
+func (r *MFARepository) Enable(userID int) ([]string, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE user_mfa SET enabled_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// Disable removes a user's enrollment and recovery codes
+func (r *MFARepository) Disable(userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AcceptStep records that a TOTP code from step was used. It returns false
+// if a code from this or a later step was already accepted.
+func (r *MFARepository) AcceptStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa SET last_step = $2
+		WHERE user_id = $1 AND (last_step IS NULL OR last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// RegenerateRecoveryCodes replaces all of a user's recovery codes
+func (r *MFARepository) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// UseRecoveryCode marks a recovery code as used. It returns false if the code
+// is unknown or was already used.
+func (r *MFARepository) UseRecoveryCode(userID int, code string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, hashToken(NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// NormalizeRecoveryCode strips the separators and case a user may type
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores new ones,
+// returning their plaintext formatted as xxxxx-xxxxx
+func replaceRecoveryCodes(tx *sqlx.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::text[])
+	`, userID, pq.Array(hashes))
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CreateChallenge starts the second login step for a user and returns the
+// challenge token
+func (r *MFARepository) CreateChallenge(userID int, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, userID, hashToken(token), time.Now().UTC().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// AttemptChallenge uses up one attempt of a challenge and returns its user.
+// It fails with ErrInvalidChallenge once the challenge is expired, completed
+// or out of attempts.
+func (r *MFARepository) AttemptChallenge(token string) (int, error) {
+	var userID int
+	err := r.db.Get(&userID, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND expires_at > $2 AND attempts < $3
+		RETURNING user_id
+	`, hashToken(token), time.Now().UTC(), MaxChallengeAttempts)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidChallenge
+	}
+	return userID, err
+}
+
+// CompleteChallenge deletes a challenge after a successful second step. It
+// fails with ErrInvalidChallenge if a concurrent request completed it first.
+func (r *MFARepository) CompleteChallenge(token string) error {
+	result, err := r.db.Exec("DELETE FROM mfa_challenges WHERE token_hash = $1", hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// PurgeExpiredChallenges deletes challenges that can no longer be used
+func (r *MFARepository) PurgeExpiredChallenges(now time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM mfa_challenges WHERE expires_at < $1", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RequiredRoles lists the roles whose members must use MFA
+func (r *MFARepository) RequiredRoles() ([]string, error) {
+	roles := []string{}
+	err := r.db.Select(&roles, "SELECT role FROM mfa_required_roles ORDER BY role")
+	return roles, err
+}
+
+// SetRequiredRoles replaces the roles whose members must use MFA
+func (r *MFARepository) SetRequiredRoles(roles []string) error {
+	// A nil array would be NULL and match no existing role
+	if roles == nil {
+		roles = []string{}
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_required_roles WHERE role <> ALL($1)", pq.Array(roles)); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO mfa_required_roles (role, created_at)
+		SELECT UNNEST($1::text[]), NOW()
+		ON CONFLICT (role) DO NOTHING
+	`, pq.Array(roles))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Notification kinds
+const (
+	NotificationReminder = "reminder"
+	NotificationOverdue  = "overdue"
+)
+
+// Notification is an in-app notification. Channels lists where else it was
+// sent.
+type Notification struct {
+	ID        int64          `db:"id" json:"id"`
+	UserID    int            `db:"user_id" json:"user_id"`
+	TaskID    *int           `db:"task_id" json:"task_id"`
+	Kind      string         `db:"kind" json:"kind"`
+	Title     string         `db:"title" json:"title"`
+	Body      string         `db:"body" json:"body"`
+	Channels  pq.StringArray `db:"channels" json:"channels"`
+	ReadAt    *time.Time     `db:"read_at" json:"read_at"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// NotificationSettings are a user's preferences for notifications that do
+// not come from reminder rules
+type NotificationSettings struct {
+	UserID          int            `db:"user_id" json:"user_id"`
+	OverdueChannels pq.StringArray `db:"overdue_channels" json:"overdue_channels"`
+	UpdatedAt       time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// NotificationRepository handles in-app notifications
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// FindByID finds a notification by ID
+func (r *NotificationRepository) FindByID(id int64) (*Notification, error) {
+	notification := &Notification{}
+	err := r.db.Get(notification, "SELECT * FROM notifications WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// ListByUser returns up to limit of a user's notifications older than
+// beforeID, or the newest if beforeID is 0, newest first
+func (r *NotificationRepository) ListByUser(userID int, unreadOnly bool, beforeID int64, limit int) ([]Notification, error) {
+	notifications := []Notification{}
+	err := r.db.Select(&notifications, `
+		SELECT * FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL) AND ($3::bigint = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`, userID, unreadOnly, beforeID, limit)
+	return notifications, err
+}
+
+// UnreadCount returns how many unread notifications a user has
+func (r *NotificationRepository) UnreadCount(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID)
+	return count, err
+}
+
+// SetRead marks a notification as read or unread
+func (r *NotificationRepository) SetRead(notification *Notification, read bool) error {
+	return r.db.QueryRowx(`
+		UPDATE notifications
+		SET read_at = CASE WHEN $1 THEN COALESCE(read_at, NOW()) END
+		WHERE id = $2
+		RETURNING read_at
+	`, read, notification.ID).Scan(&notification.ReadAt)
+}
+
+// MarkAllRead marks every notification of a user as read and returns how
+// many were unread
+func (r *NotificationRepository) MarkAllRead(userID int) (int64, error) {
+	result, err := r.db.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Delete removes a notification
+func (r *NotificationRepository) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM notifications WHERE id = $1", id)
+	return err
+}
+
+// GetSettings returns a user's notification settings, or the defaults if
+// they have not saved any
+func (r *NotificationRepository) GetSettings(userID int) (*NotificationSettings, error) {
+	settings := &NotificationSettings{}
+	err := r.db.Get(settings, `
+		SELECT $1::int AS user_id, COALESCE(s.overdue_channels, '{email}') AS overdue_channels, COALESCE(s.updated_at, NOW()) AS updated_at
+		FROM (SELECT 1) one
+		LEFT JOIN notification_settings s ON s.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSettings saves a user's notification settings
+func (r *NotificationRepository) SaveSettings(settings *NotificationSettings) error {
+	return r.db.QueryRowx(`
+		INSERT INTO notification_settings (user_id, overdue_channels, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET overdue_channels = EXCLUDED.overdue_channels, updated_at = NOW()
+		RETURNING updated_at
+	`, settings.UserID, settings.OverdueChannels).Scan(&settings.UpdatedAt)
+}
+
+// FlagOverdue flags up to limit open tasks whose due date has passed and
+// notifies their assignees, or their owner if nobody is assigned. It
+// returns the notifications. Each task is flagged once until its due date
+// changes or it is reopened.
+func (r *NotificationRepository) FlagOverdue(limit int) ([]Notification, error) {
+	notifications := []Notification{}
+	err := r.db.Select(&notifications, `
+		WITH late AS (
+			SELECT id FROM tasks
+			WHERE overdue_at IS NULL AND due_date < NOW() AT TIME ZONE 'UTC' AND status <> ALL($1)
+			ORDER BY due_date
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		),
+		flagged AS (
+			UPDATE tasks t SET overdue_at = NOW()
+			FROM late WHERE t.id = late.id
+			RETURNING t.id, t.user_id, t.title, t.due_date
+		),
+		recipients AS (
+			SELECT f.*, a.user_id AS recipient_id
+			FROM flagged f JOIN task_assignees a ON a.task_id = f.id
+			UNION
+			SELECT f.*, f.user_id
+			FROM flagged f
+			WHERE NOT EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = f.id)
+		)
+		INSERT INTO notifications (user_id, task_id, kind, title, body, channels, created_at)
+		SELECT rc.recipient_id, rc.id, $3::text, 'Overdue: ' || rc.title,
+			'Was due ' || to_char(rc.due_date, 'YYYY-MM-DD HH24:MI') || ' UTC',
+			COALESCE(s.overdue_channels, '{email}'), NOW()
+		FROM recipients rc
+		LEFT JOIN notification_settings s ON s.user_id = rc.recipient_id
+		RETURNING *
+	`, closedStatuses, limit, NotificationOverdue)
+	return notifications, err
+}
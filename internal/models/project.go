@@ -0,0 +1,323 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Project membership roles
+const (
+	ProjectOwner  = "owner"
+	ProjectEditor = "editor"
+	ProjectViewer = "viewer"
+)
+
+// ErrLastOwner is returned when a change would leave a project without an owner
+var ErrLastOwner = errors.New("project must keep at least one owner")
+
+// AccessLevel is what a user may do with a task or project
+type AccessLevel int
+
+// Access levels, from least to most privileged
+const (
+	AccessNone AccessLevel = iota
+	AccessRead
+	AccessWrite
+	AccessManage
+)
+
+// roleAccess maps membership roles to access levels
+var roleAccess = map[string]AccessLevel{
+	ProjectViewer: AccessRead,
+	ProjectEditor: AccessWrite,
+	ProjectOwner:  AccessManage,
+}
+
+// Grants is the access a user's role gives regardless of project membership
+type Grants struct {
+	// AnyTask applies to every task
+	AnyTask AccessLevel
+	// OwnTask applies to the user's personal tasks
+	OwnTask AccessLevel
+	// AnyProject applies to every project and its tasks
+	AnyProject AccessLevel
+}
+
+// Project is a shared workspace whose tasks are visible to its members
+type Project struct {
+	ID          int       `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name" validate:"required,min=1,max=100"`
+	Description string    `db:"description" json:"description"`
+	CreatedBy   *int      `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ProjectMember is a user's membership in a project
+type ProjectMember struct {
+	ProjectID int       `db:"project_id" json:"project_id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProjectRepository handles projects, membership and access checks
+type ProjectRepository struct {
+	db *sqlx.DB
+}
+
+// NewProjectRepository creates a new project repository
+func NewProjectRepository(db *sqlx.DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+// Create adds a project and makes ownerID its owner
+func (r *ProjectRepository) Create(project *Project, ownerID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	project.CreatedBy = &ownerID
+	err = tx.QueryRowx(`
+		INSERT INTO projects (name, description, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, project.Name, project.Description, project.CreatedBy).Scan(&project.ID, &project.CreatedAt, &project.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO project_members (project_id, user_id, role, created_at) VALUES ($1, $2, $3, NOW())",
+		project.ID, ownerID, ProjectOwner,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindByID finds a project by ID
+func (r *ProjectRepository) FindByID(id int) (*Project, error) {
+	project := &Project{}
+	err := r.db.Get(project, "SELECT * FROM projects WHERE id = $1", id)
+	return project, err
+}
+
+// ListForUser returns the projects a user is a member of
+func (r *ProjectRepository) ListForUser(userID int) ([]Project, error) {
+	projects := []Project{}
+	err := r.db.Select(&projects, `
+		SELECT p.* FROM projects p
+		JOIN project_members m ON m.project_id = p.id
+		WHERE m.user_id = $1
+		ORDER BY p.name, p.id
+	`, userID)
+	return projects, err
+}
+
+// ListAll returns every project
+func (r *ProjectRepository) ListAll() ([]Project, error) {
+	projects := []Project{}
+	err := r.db.Select(&projects, "SELECT * FROM projects ORDER BY name, id")
+	return projects, err
+}
+
+// Update modifies a project's name and description
+func (r *ProjectRepository) Update(project *Project) error {
+	return r.db.QueryRowx(
+		"UPDATE projects SET name = $1, description = $2, updated_at = NOW() WHERE id = $3 RETURNING updated_at",
+		project.Name, project.Description, project.ID,
+	).Scan(&project.UpdatedAt)
+}
+
+// Delete removes a project together with its tasks
+func (r *ProjectRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM projects WHERE id = $1", id)
+	return err
+}
+
+// Members returns the members of a project
+func (r *ProjectRepository) Members(projectID int) ([]ProjectMember, error) {
+	members := []ProjectMember{}
+	err := r.db.Select(&members, `
+		SELECT m.project_id, m.user_id, u.username, m.role, m.created_at
+		FROM project_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.project_id = $1
+		ORDER BY u.username
+	`, projectID)
+	return members, err
+}
+
+// MemberRole returns a user's role in a project, or "" if not a member
+func (r *ProjectRepository) MemberRole(projectID, userID int) (string, error) {
+	var role string
+	err := r.db.Get(&role, "SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2", projectID, userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// SetMember adds a member or changes an existing member's role
+func (r *ProjectRepository) SetMember(projectID, userID int, role string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO project_members (project_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`, projectID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureOwner(tx, projectID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveMember removes a user from a project
+func (r *ProjectRepository) RemoveMember(projectID, userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM project_members WHERE project_id = $1 AND user_id = $2", projectID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := ensureOwner(tx, projectID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ensureOwner returns ErrLastOwner if the project has no owner left
+func ensureOwner(tx *sqlx.Tx, projectID int) error {
+	var owners int
+	err := tx.Get(&owners,
+		"SELECT COUNT(*) FROM project_members WHERE project_id = $1 AND role = $2",
+		projectID, ProjectOwner,
+	)
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// ProjectAccess returns what a user may do in a project
+func (r *ProjectRepository) ProjectAccess(projectID, userID int, grants Grants) (AccessLevel, error) {
+	if grants.AnyProject == AccessManage {
+		return AccessManage, nil
+	}
+
+	role, err := r.MemberRole(projectID, userID)
+	if err != nil {
+		return AccessNone, err
+	}
+	return maxAccess(roleAccess[role], grants.AnyProject), nil
+}
+
+// TaskAudience lists the users who may see a task through membership or
+// assignment rather than through their role
+type TaskAudience struct {
+	MemberIDs   []int // members of the task's project
+	AssigneeIDs []int
+}
+
+// TaskAudience returns who may see task through membership or assignment
+func (r *ProjectRepository) TaskAudience(task *Task) (*TaskAudience, error) {
+	audience := &TaskAudience{MemberIDs: []int{}, AssigneeIDs: []int{}}
+	if task.ProjectID != nil {
+		err := r.db.Select(&audience.MemberIDs,
+			"SELECT user_id FROM project_members WHERE project_id = $1 ORDER BY user_id", *task.ProjectID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := r.db.Select(&audience.AssigneeIDs,
+		"SELECT user_id FROM task_assignees WHERE task_id = $1 ORDER BY user_id", task.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return audience, nil
+}
+
+// TaskAccess returns what a user may do with a task. Personal tasks belong to
+// their owner; project tasks follow the user's project role. Assignees get
+// what grants.OwnTask allows, short of deleting, and grants raise the result.
+func (r *ProjectRepository) TaskAccess(task *Task, userID int, grants Grants) (AccessLevel, error) {
+	if grants.AnyTask == AccessManage {
+		return AccessManage, nil
+	}
+
+	access := AccessNone
+	if task.ProjectID == nil {
+		if task.UserID == userID {
+			access = grants.OwnTask
+		}
+	} else {
+		var err error
+		access, err = r.ProjectAccess(*task.ProjectID, userID, grants)
+		if err != nil {
+			return AccessNone, err
+		}
+	}
+
+	if assigneeAccess := minAccess(grants.OwnTask, AccessWrite); access < assigneeAccess {
+		var assigned bool
+		err := r.db.Get(&assigned,
+			"SELECT EXISTS (SELECT 1 FROM task_assignees WHERE task_id = $1 AND user_id = $2)",
+			task.ID, userID,
+		)
+		if err != nil {
+			return AccessNone, err
+		}
+		if assigned {
+			access = assigneeAccess
+		}
+	}
+
+	return maxAccess(access, grants.AnyTask), nil
+}
+
+// minAccess returns the lower of two access levels
+func minAccess(a, b AccessLevel) AccessLevel {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxAccess returns the higher of two access levels
+func maxAccess(a, b AccessLevel) AccessLevel {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrBuiltinRole is returned when deleting a built-in role
+	ErrBuiltinRole = errors.New("built-in roles cannot be deleted")
+	// ErrRoleInUse is returned when deleting a role that users still have
+	ErrRoleInUse = errors.New("role is assigned to users")
+	// ErrRoleExists is returned when creating a role whose name is taken
+	ErrRoleExists = errors.New("role already exists")
+	// ErrUnknownPermission is returned when granting a permission that does not exist
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+// Role is a named set of permissions assigned to users
+type Role struct {
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Builtin     bool      `db:"builtin" json:"builtin"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	Permissions []string  `db:"-" json:"permissions"`
+}
+
+// Permission is an action a role can be granted
+type Permission struct {
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+}
+
+// RBACRepository handles roles and their permission grants
+type RBACRepository struct {
+	db *sqlx.DB
+}
+
+// NewRBACRepository creates a new RBAC repository
+func NewRBACRepository(db *sqlx.DB) *RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// ListPermissions returns every permission that can be granted
+func (r *RBACRepository) ListPermissions() ([]Permission, error) {
+	permissions := []Permission{}
+	err := r.db.Select(&permissions, "SELECT * FROM permissions ORDER BY name")
+	return permissions, err
+}
+
+// Grants returns the permissions of every role
+func (r *RBACRepository) Grants() (map[string][]string, error) {
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	if err := r.db.Select(&rows, "SELECT role, permission FROM role_permissions ORDER BY role, permission"); err != nil {
+		return nil, err
+	}
+
+	grants := make(map[string][]string)
+	for _, row := range rows {
+		grants[row.Role] = append(grants[row.Role], row.Permission)
+	}
+	return grants, nil
+}
+
+// ListRoles returns every role with its permissions
+func (r *RBACRepository) ListRoles() ([]Role, error) {
+	roles := []Role{}
+	if err := r.db.Select(&roles, "SELECT * FROM roles ORDER BY name"); err != nil {
+		return nil, err
+	}
+
+	grants, err := r.Grants()
+	if err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		roles[i].Permissions = grants[roles[i].Name]
+		if roles[i].Permissions == nil {
+			roles[i].Permissions = []string{}
+		}
+	}
+	return roles, nil
+}
+
+// FindRole finds a role by name with its permissions
+func (r *RBACRepository) FindRole(name string) (*Role, error) {
+	role := &Role{}
+	if err := r.db.Get(role, "SELECT * FROM roles WHERE name = $1", name); err != nil {
+		return nil, err
+	}
+
+	role.Permissions = []string{}
+	err := r.db.Select(&role.Permissions, "SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission", name)
+	return role, err
+}
+
+// RoleExists reports whether a role with this name exists
+func (r *RBACRepository) RoleExists(name string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", name)
+	return exists, err
+}
+
+// CreateRole adds a role with its permissions
+func (r *RBACRepository) CreateRole(role *Role) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowx(`
+		INSERT INTO roles (name, description, builtin, created_at)
+		VALUES ($1, $2, FALSE, NOW())
+		ON CONFLICT (name) DO NOTHING
+		RETURNING created_at
+	`, role.Name, role.Description).Scan(&role.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrRoleExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := setGrants(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRole changes a role's description and replaces its permissions
+func (r *RBACRepository) UpdateRole(role *Role) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowx(
+		"UPDATE roles SET description = $1 WHERE name = $2 RETURNING builtin, created_at",
+		role.Description, role.Name,
+	).Scan(&role.Builtin, &role.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role = $1", role.Name); err != nil {
+		return err
+	}
+	if err := setGrants(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRole removes a role that is neither built in nor assigned to users
+func (r *RBACRepository) DeleteRole(name string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var builtin bool
+	if err := tx.Get(&builtin, "SELECT builtin FROM roles WHERE name = $1 FOR UPDATE", name); err != nil {
+		return err
+	}
+	if builtin {
+		return ErrBuiltinRole
+	}
+
+	var inUse bool
+	if err := tx.Get(&inUse, "SELECT EXISTS(SELECT 1 FROM users WHERE role = $1)", name); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrRoleInUse
+	}
+
+	if _, err := tx.Exec("DELETE FROM roles WHERE name = $1", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setGrants grants permissions to role, failing with ErrUnknownPermission if
+// any of them does not exist
+func setGrants(tx *sqlx.Tx, role string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO role_permissions (role, permission)
+		SELECT $1, name FROM permissions WHERE name = ANY($2)
+	`, role, pq.Array(permissions))
+	if err != nil {
+		return err
+	}
+
+	granted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(granted) != len(uniqueStrings(permissions)) {
+		return ErrUnknownPermission
+	}
+	return nil
+}
+
+// uniqueStrings returns values without duplicates, keeping the first of each
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
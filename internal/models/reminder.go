@@ -0,0 +1,213 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Channels notifications can be sent through besides in-app
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// IsValidChannel reports whether c is a known notification channel
+func IsValidChannel(c string) bool {
+	switch c {
+	case ChannelEmail, ChannelWebhook:
+		return true
+	}
+	return false
+}
+
+// closedStatuses are the statuses of tasks nobody needs reminding of
+var closedStatuses = pq.StringArray{StatusDone, StatusCancelled}
+
+// ReminderRule says when a user wants to be reminded of tasks coming due:
+// MinutesBefore the due date, or at TimeOfDay ("09:00") in Timezone,
+// DaysBefore the due date
+type ReminderRule struct {
+	ID            int            `db:"id" json:"id"`
+	UserID        int            `db:"user_id" json:"user_id"`
+	MinutesBefore *int           `db:"minutes_before" json:"minutes_before"`
+	DaysBefore    int            `db:"days_before" json:"days_before"`
+	TimeOfDay     *string        `db:"time_of_day" json:"time_of_day"`
+	Timezone      string         `db:"timezone" json:"timezone"`
+	Channels      pq.StringArray `db:"channels" json:"channels"`
+	Active        bool           `db:"active" json:"active"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// ReminderRepository handles reminder rules and the reminders they schedule
+type ReminderRepository struct {
+	db *sqlx.DB
+}
+
+// NewReminderRepository creates a new reminder repository
+func NewReminderRepository(db *sqlx.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// ruleColumns selects a rule with its time of day as HH:MM
+const ruleColumns = `id, user_id, minutes_before, days_before, to_char(time_of_day, 'HH24:MI') AS time_of_day,
+	timezone, channels, active, created_at, updated_at`
+
+// CreateRule adds a reminder rule
+func (r *ReminderRepository) CreateRule(rule *ReminderRule) error {
+	return r.db.QueryRowx(`
+		INSERT INTO reminder_rules (user_id, minutes_before, days_before, time_of_day, timezone, channels, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, rule.UserID, rule.MinutesBefore, rule.DaysBefore, rule.TimeOfDay, rule.Timezone, rule.Channels, rule.Active).
+		Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+// FindRule finds a user's reminder rule by ID
+func (r *ReminderRepository) FindRule(id, userID int) (*ReminderRule, error) {
+	rule := &ReminderRule{}
+	err := r.db.Get(rule, "SELECT "+ruleColumns+" FROM reminder_rules WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListRules returns a user's reminder rules
+func (r *ReminderRepository) ListRules(userID int) ([]ReminderRule, error) {
+	rules := []ReminderRule{}
+	err := r.db.Select(&rules, "SELECT "+ruleColumns+" FROM reminder_rules WHERE user_id = $1 ORDER BY id", userID)
+	return rules, err
+}
+
+// CountRules returns how many reminder rules a user has
+func (r *ReminderRepository) CountRules(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM reminder_rules WHERE user_id = $1", userID)
+	return count, err
+}
+
+// UpdateRule saves a reminder rule
+func (r *ReminderRepository) UpdateRule(rule *ReminderRule) error {
+	return r.db.QueryRowx(`
+		UPDATE reminder_rules
+		SET minutes_before = $1, days_before = $2, time_of_day = $3, timezone = $4, channels = $5, active = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING updated_at
+	`, rule.MinutesBefore, rule.DaysBefore, rule.TimeOfDay, rule.Timezone, rule.Channels, rule.Active, rule.ID).
+		Scan(&rule.UpdatedAt)
+}
+
+// DeleteRule removes a reminder rule and its pending reminders
+func (r *ReminderRepository) DeleteRule(id int) error {
+	_, err := r.db.Exec("DELETE FROM reminder_rules WHERE id = $1", id)
+	return err
+}
+
+// ValidTimezone reports whether the database knows the named time zone
+func (r *ReminderRepository) ValidTimezone(name string) (bool, error) {
+	var ok bool
+	err := r.db.Get(&ok, "SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = $1)", name)
+	return ok, err
+}
+
+// ScheduleForTasks recomputes the reminders of the given tasks, after their
+// due dates, statuses or assignees changed
+func (r *ReminderRepository) ScheduleForTasks(taskIDs ...int) error {
+	ids := make(pq.Int64Array, len(taskIDs))
+	for i, id := range taskIDs {
+		ids[i] = int64(id)
+	}
+	return r.schedule("task_id = ANY($1)", ids)
+}
+
+// ScheduleForUser recomputes the reminders of a user, after their rules
+// changed
+func (r *ReminderRepository) ScheduleForUser(userID int) error {
+	return r.schedule("user_id = $1", userID)
+}
+
+// schedule recomputes the reminders matching filter in a transaction
+func (r *ReminderRepository) schedule(filter string, arg interface{}) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := scheduleReminders(tx, filter, arg); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scheduleReminders recomputes the reminders matching filter, which refers
+// to task_id and user_id with arg as $1. Reminders that are due again after
+// a change are sent again; reminders that would be due in the past are
+// dropped.
+func scheduleReminders(tx sqlx.Execer, filter string, arg interface{}) error {
+	if _, err := tx.Exec("DELETE FROM reminders WHERE sent_at IS NULL AND "+filter, arg); err != nil {
+		return err
+	}
+
+	// Assignees are reminded, or the owner of a task nobody is assigned to
+	_, err := tx.Exec(`
+		WITH recipients AS (
+			SELECT t.id AS task_id, t.due_date, a.user_id
+			FROM tasks t JOIN task_assignees a ON a.task_id = t.id
+			WHERE t.due_date IS NOT NULL AND t.status <> ALL($2)
+			UNION
+			SELECT t.id, t.due_date, t.user_id
+			FROM tasks t
+			WHERE t.due_date IS NOT NULL AND t.status <> ALL($2)
+				AND NOT EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = t.id)
+		)
+		INSERT INTO reminders (task_id, rule_id, user_id, remind_at)
+		SELECT task_id, rule_id, user_id, remind_at FROM (
+			SELECT rc.task_id, rr.id AS rule_id, rc.user_id,
+				CASE WHEN rr.minutes_before IS NOT NULL
+					THEN rc.due_date - make_interval(mins => rr.minutes_before)
+					ELSE ((date_trunc('day', rc.due_date AT TIME ZONE 'UTC' AT TIME ZONE rr.timezone)
+						- make_interval(days => rr.days_before) + rr.time_of_day) AT TIME ZONE rr.timezone) AT TIME ZONE 'UTC'
+				END AS remind_at
+			FROM recipients rc
+			JOIN reminder_rules rr ON rr.user_id = rc.user_id AND rr.active
+		) due
+		WHERE `+filter+` AND remind_at > NOW() AT TIME ZONE 'UTC'
+		ON CONFLICT (task_id, rule_id, user_id) DO UPDATE
+			SET remind_at = EXCLUDED.remind_at, sent_at = NULL
+			WHERE reminders.remind_at <> EXCLUDED.remind_at
+	`, arg, closedStatuses)
+	return err
+}
+
+// SendDue turns up to limit due reminders into notifications and returns
+// them. Reminders of tasks that were closed meanwhile are dropped.
+func (r *ReminderRepository) SendDue(limit int) ([]Notification, error) {
+	notifications := []Notification{}
+	err := r.db.Select(&notifications, `
+		WITH due AS (
+			SELECT id FROM reminders
+			WHERE sent_at IS NULL AND remind_at <= NOW() AT TIME ZONE 'UTC'
+			ORDER BY remind_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		),
+		sent AS (
+			UPDATE reminders r SET sent_at = NOW() AT TIME ZONE 'UTC'
+			FROM due WHERE r.id = due.id
+			RETURNING r.task_id, r.rule_id, r.user_id
+		)
+		INSERT INTO notifications (user_id, task_id, kind, title, body, channels, created_at)
+		SELECT s.user_id, t.id, $3::text, 'Reminder: ' || t.title,
+			'Due ' || to_char(t.due_date, 'YYYY-MM-DD HH24:MI') || ' UTC', rr.channels, NOW()
+		FROM sent s
+		JOIN tasks t ON t.id = s.task_id
+		JOIN reminder_rules rr ON rr.id = s.rule_id
+		WHERE t.status <> ALL($1) AND t.due_date IS NOT NULL
+		RETURNING *
+	`, closedStatuses, limit, NotificationReminder)
+	return notifications, err
+}
@@ -0,0 +1,473 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/Task_Management/internal/recurrence"
+)
+
+// ErrNotRecurring is returned when a series operation targets a one-off task
+var ErrNotRecurring = errors.New("task is not part of a recurring series")
+
+// TaskSeries is a recurring task template. The scheduler materializes its
+// occurrences into ordinary tasks linked back through Task.SeriesID.
+type TaskSeries struct {
+	ID               int        `db:"id" json:"id"`
+	UserID           int        `db:"user_id" json:"user_id"`
+	ProjectID        *int       `db:"project_id" json:"project_id"`
+	Title            string     `db:"title" json:"title" validate:"required,min=3,max=100"`
+	Description      string     `db:"description" json:"description"`
+	CategoryID       *int       `db:"category_id" json:"category_id"`
+	Priority         string     `db:"priority" json:"priority" validate:"omitempty,oneof=low normal high urgent"`
+	EstimateMinutes  *int       `db:"estimate_minutes" json:"estimate_minutes" validate:"omitempty,min=0"`
+	RRule            string     `db:"rrule" json:"rrule" validate:"required,max=255"`
+	DTStart          time.Time  `db:"dtstart" json:"dtstart" validate:"required"`
+	LeadMinutes      int        `db:"lead_minutes" json:"lead_minutes" validate:"min=0"`
+	NextOccurrenceAt *time.Time `db:"next_occurrence_at" json:"next_occurrence_at"`
+	Active           bool       `db:"active" json:"active"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Rule parses the series' recurrence rule
+func (s *TaskSeries) Rule() (*recurrence.Rule, error) {
+	return recurrence.Parse(s.RRule, s.DTStart)
+}
+
+// SeriesChanges holds optional template edits for a series. Nil fields are
+// left unchanged.
+type SeriesChanges struct {
+	Title           *string `json:"title" validate:"omitempty,min=3,max=100"`
+	Description     *string `json:"description"`
+	CategoryID      *int    `json:"category_id"`
+	Priority        *string `json:"priority" validate:"omitempty,oneof=low normal high urgent"`
+	EstimateMinutes *int    `json:"estimate_minutes" validate:"omitempty,min=0"`
+	RRule           *string `json:"rrule" validate:"omitempty,max=255"`
+	LeadMinutes     *int    `json:"lead_minutes" validate:"omitempty,min=0"`
+}
+
+// Apply copies the non-nil template fields onto series
+func (c *SeriesChanges) Apply(series *TaskSeries) {
+	if c.Title != nil {
+		series.Title = *c.Title
+	}
+	if c.Description != nil {
+		series.Description = *c.Description
+	}
+	if c.CategoryID != nil {
+		series.CategoryID = c.CategoryID
+	}
+	if c.Priority != nil {
+		series.Priority = *c.Priority
+	}
+	if c.EstimateMinutes != nil {
+		series.EstimateMinutes = c.EstimateMinutes
+	}
+	if c.RRule != nil {
+		series.RRule = *c.RRule
+	}
+	if c.LeadMinutes != nil {
+		series.LeadMinutes = *c.LeadMinutes
+	}
+}
+
+// ApplyToTask copies the non-nil task fields onto an occurrence
+func (c *SeriesChanges) ApplyToTask(task *Task) {
+	if c.Title != nil {
+		task.Title = *c.Title
+	}
+	if c.Description != nil {
+		task.Description = *c.Description
+	}
+	if c.CategoryID != nil {
+		task.CategoryID = c.CategoryID
+	}
+	if c.Priority != nil {
+		task.Priority = *c.Priority
+	}
+	if c.EstimateMinutes != nil {
+		task.EstimateMinutes = c.EstimateMinutes
+	}
+}
+
+// SeriesRepository handles database operations for recurring task series
+type SeriesRepository struct {
+	db *sqlx.DB
+}
+
+// NewSeriesRepository creates a new series repository
+func NewSeriesRepository(db *sqlx.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+// insertSeries writes a new series row using q
+func insertSeries(q sqlx.Queryer, series *TaskSeries) error {
+	query := `
+		INSERT INTO task_series (user_id, project_id, title, description, category_id, priority, estimate_minutes,
+			rrule, dtstart, lead_minutes, next_occurrence_at, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	return q.QueryRowx(
+		query,
+		series.UserID,
+		series.ProjectID,
+		series.Title,
+		series.Description,
+		series.CategoryID,
+		series.Priority,
+		series.EstimateMinutes,
+		series.RRule,
+		series.DTStart,
+		series.LeadMinutes,
+		series.NextOccurrenceAt,
+		series.Active,
+	).Scan(&series.ID, &series.CreatedAt, &series.UpdatedAt)
+}
+
+// Create adds a new series and schedules its first occurrence
+func (r *SeriesRepository) Create(series *TaskSeries) error {
+	rule, err := series.Rule()
+	if err != nil {
+		return err
+	}
+
+	series.Active = true
+	series.NextOccurrenceAt = nil
+	if first, ok := rule.First(); ok {
+		series.NextOccurrenceAt = &first
+	}
+
+	return insertSeries(r.db, series)
+}
+
+// FindByID finds a series by ID
+func (r *SeriesRepository) FindByID(id int) (*TaskSeries, error) {
+	series := &TaskSeries{}
+	err := r.db.Get(series, "SELECT * FROM task_series WHERE id = $1", id)
+	return series, err
+}
+
+// ListByUser returns the series owned by a user
+func (r *SeriesRepository) ListByUser(userID int) ([]TaskSeries, error) {
+	series := []TaskSeries{}
+	err := r.db.Select(&series, "SELECT * FROM task_series WHERE user_id = $1 ORDER BY created_at, id", userID)
+	return series, err
+}
+
+// Occurrences returns the tasks generated by a series, oldest first
+func (r *SeriesRepository) Occurrences(seriesID int) ([]Task, error) {
+	tasks := []Task{}
+	err := r.db.Select(&tasks, "SELECT "+taskSelect+" FROM tasks WHERE series_id = $1 ORDER BY occurrence_at, id", seriesID)
+	return tasks, err
+}
+
+// Update saves template changes for future occurrences. If the rule changed,
+// the next occurrence is recomputed from the last one already generated.
+func (r *SeriesRepository) Update(series *TaskSeries) error {
+	rule, err := series.Rule()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last sql.NullTime
+	if err := tx.Get(&last, "SELECT MAX(occurrence_at) FROM tasks WHERE series_id = $1", series.ID); err != nil {
+		return err
+	}
+
+	series.NextOccurrenceAt = nil
+	var next time.Time
+	var ok bool
+	if last.Valid {
+		next, ok = rule.After(last.Time)
+	} else {
+		next, ok = rule.First()
+	}
+	if ok && series.Active {
+		series.NextOccurrenceAt = &next
+	}
+
+	query := `
+		UPDATE task_series
+		SET title = $1, description = $2, category_id = $3, priority = $4, estimate_minutes = $5,
+			rrule = $6, lead_minutes = $7, next_occurrence_at = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+	err = tx.QueryRowx(
+		query,
+		series.Title,
+		series.Description,
+		series.CategoryID,
+		series.Priority,
+		series.EstimateMinutes,
+		series.RRule,
+		series.LeadMinutes,
+		series.NextOccurrenceAt,
+		series.ID,
+	).Scan(&series.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a series. Tasks it generated are kept as one-off tasks.
+func (r *SeriesRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM task_series WHERE id = $1", id)
+	return err
+}
+
+// MaterializeDue creates the next occurrence of every series that is due,
+// either because its lead window has opened or because its previous
+// occurrence has been closed. It returns the IDs of the tasks created.
+func (r *SeriesRepository) MaterializeDue(now time.Time) ([]int, error) {
+	var created []int
+	for {
+		taskID, ok, err := r.materializeNext(now)
+		if err != nil {
+			return created, err
+		}
+		if !ok {
+			return created, nil
+		}
+		if taskID != 0 {
+			created = append(created, taskID)
+		}
+	}
+}
+
+// materializeNext creates one due occurrence and returns its ID, which is 0
+// if the series was stopped instead. SKIP LOCKED lets several API replicas
+// run the scheduler without generating duplicates.
+func (r *SeriesRepository) materializeNext(now time.Time) (int, bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	series := &TaskSeries{}
+	err = tx.Get(series, `
+		SELECT s.* FROM task_series s
+		WHERE s.active AND s.next_occurrence_at IS NOT NULL
+			AND (
+				s.next_occurrence_at - s.lead_minutes * INTERVAL '1 minute' <= $1
+				OR NOT EXISTS (
+					SELECT 1 FROM tasks t
+					WHERE t.series_id = s.id AND t.status NOT IN ($2, $3)
+				)
+			)
+		ORDER BY s.next_occurrence_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, now, StatusDone, StatusCancelled)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// A project series stops once its owner may no longer add tasks to the
+	// project
+	if series.ProjectID != nil {
+		var role string
+		err := tx.Get(&role,
+			"SELECT COALESCE((SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2), '')",
+			*series.ProjectID, series.UserID,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+		if roleAccess[role] < AccessWrite {
+			_, err = tx.Exec(
+				"UPDATE task_series SET next_occurrence_at = NULL, active = FALSE, updated_at = NOW() WHERE id = $1",
+				series.ID,
+			)
+			if err != nil {
+				return 0, false, err
+			}
+			return 0, true, tx.Commit()
+		}
+	}
+
+	occurrenceAt := *series.NextOccurrenceAt
+	task := Task{
+		Title:           series.Title,
+		Description:     series.Description,
+		UserID:          series.UserID,
+		CreatedBy:       &series.UserID,
+		ProjectID:       series.ProjectID,
+		CategoryID:      series.CategoryID,
+		Status:          StatusPending,
+		Priority:        series.Priority,
+		EstimateMinutes: series.EstimateMinutes,
+		DueDate:         &occurrenceAt,
+		SeriesID:        &series.ID,
+		OccurrenceAt:    &occurrenceAt,
+	}
+	err = tx.QueryRowx(`
+		INSERT INTO tasks (title, description, user_id, created_by, project_id, category_id, status, priority,
+			estimate_minutes, due_date, series_id, occurrence_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING id
+	`,
+		task.Title, task.Description, task.UserID, task.CreatedBy, task.ProjectID, task.CategoryID, task.Status,
+		task.Priority, task.EstimateMinutes, task.DueDate, task.SeriesID, task.OccurrenceAt,
+	).Scan(&task.ID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Occurrences are assigned to the series owner
+	_, err = tx.Exec(
+		"INSERT INTO task_assignees (task_id, user_id, assigned_by, assigned_at) VALUES ($1, $2, NULL, NOW())",
+		task.ID, task.UserID,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO task_status_history (task_id, from_status, to_status, changed_by, changed_at)
+		 VALUES ($1, NULL, $2, NULL, NOW())`,
+		task.ID, task.Status,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := scheduleReminders(tx, "task_id = $1", task.ID); err != nil {
+		return 0, false, err
+	}
+
+	// Occurrences missed while nothing was running are skipped rather than
+	// generated in a burst
+	var next *time.Time
+	rule, err := series.Rule()
+	if err == nil {
+		after := occurrenceAt
+		if now.After(after) {
+			after = now
+		}
+		if t, ok := rule.After(after); ok {
+			next = &t
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE task_series SET next_occurrence_at = $1, active = $2, updated_at = NOW() WHERE id = $3",
+		next, next != nil, series.ID,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return task.ID, true, tx.Commit()
+}
+
+// SplitFollowing ends the series of task at task's occurrence and starts a
+// new series there with changes applied, moving task and every later
+// occurrence to it ("edit this and following").
+func (r *SeriesRepository) SplitFollowing(task *Task, changes *SeriesChanges) (*TaskSeries, error) {
+	if task.SeriesID == nil || task.OccurrenceAt == nil {
+		return nil, ErrNotRecurring
+	}
+	splitAt := *task.OccurrenceAt
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old := &TaskSeries{}
+	if err := tx.Get(old, "SELECT * FROM task_series WHERE id = $1 FOR UPDATE", *task.SeriesID); err != nil {
+		return nil, err
+	}
+	oldRule, err := old.Rule()
+	if err != nil {
+		return nil, err
+	}
+
+	// The new series starts at this occurrence and keeps whatever is left of
+	// a COUNT-bounded rule unless a new rule is supplied
+	series := *old
+	series.DTStart = splitAt
+	if changes.RRule == nil && oldRule.Count > 0 {
+		continued := *oldRule
+		continued.Count = oldRule.Remaining(splitAt)
+		continued.DTStart = splitAt
+		series.RRule = continued.String()
+	}
+	changes.Apply(&series)
+
+	rule, err := series.Rule()
+	if err != nil {
+		return nil, err
+	}
+
+	// The new series continues after the last occurrence it takes over
+	var last sql.NullTime
+	if err := tx.Get(&last,
+		"SELECT MAX(occurrence_at) FROM tasks WHERE series_id = $1 AND occurrence_at >= $2",
+		old.ID, splitAt,
+	); err != nil {
+		return nil, err
+	}
+
+	series.Active = true
+	series.NextOccurrenceAt = nil
+	if next, ok := rule.After(last.Time); ok && last.Valid {
+		series.NextOccurrenceAt = &next
+	}
+	if err := insertSeries(tx, &series); err != nil {
+		return nil, err
+	}
+
+	// Move this and later occurrences, updating this one and any still open
+	_, err = tx.Exec(`
+		UPDATE tasks
+		SET series_id = $1,
+			title = CASE WHEN id = $4 OR status NOT IN ($5, $6) THEN $7 ELSE title END,
+			description = CASE WHEN id = $4 OR status NOT IN ($5, $6) THEN $8 ELSE description END,
+			category_id = CASE WHEN id = $4 OR status NOT IN ($5, $6) THEN $9 ELSE category_id END,
+			priority = CASE WHEN id = $4 OR status NOT IN ($5, $6) THEN $10 ELSE priority END,
+			estimate_minutes = CASE WHEN id = $4 OR status NOT IN ($5, $6) THEN $11 ELSE estimate_minutes END,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE series_id = $2 AND occurrence_at >= $3
+	`,
+		series.ID, old.ID, splitAt, task.ID, StatusDone, StatusCancelled,
+		series.Title, series.Description, series.CategoryID, series.Priority, series.EstimateMinutes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the old series just before the split point
+	until := splitAt.Add(-time.Second)
+	oldRule.Count = 0
+	oldRule.Until = &until
+	_, err = tx.Exec(
+		"UPDATE task_series SET rrule = $1, next_occurrence_at = NULL, active = FALSE, updated_at = NOW() WHERE id = $2",
+		oldRule.String(), old.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &series, tx.Commit()
+}
@@ -1,22 +1,83 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
+	"strings"
 	"time"
 	
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrVersionConflict is returned when a task was modified since it was read
+var ErrVersionConflict = errors.New("task was modified by another request")
+
+// Task priorities
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// DefaultLanguage is the text search configuration used to stem tasks that
+// do not name one
+const DefaultLanguage = "english"
+
+// IsValidPriority reports whether p is a known task priority
+func IsValidPriority(p string) bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
 // Task represents a task in the system
 type Task struct {
-	ID          int        `db:"id" json:"id"`
-	Title       string     `db:"title" json:"title" validate:"required,min=3,max=100"`
-	Description string     `db:"description" json:"description"`
-	UserID      int        `db:"user_id" json:"user_id"`
-	CategoryID  *int       `db:"category_id" json:"category_id"`
-	Status      string     `db:"status" json:"status"`
-	DueDate     *time.Time `db:"due_date" json:"due_date"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	ID              int        `db:"id" json:"id"`
+	Title           string     `db:"title" json:"title" validate:"required,min=3,max=100"`
+	Description     string     `db:"description" json:"description"`
+	UserID          int        `db:"user_id" json:"user_id"`
+	CreatedBy       *int       `db:"created_by" json:"created_by"`
+	ProjectID       *int       `db:"project_id" json:"project_id"`
+	ParentID        *int       `db:"parent_id" json:"parent_id"`
+	CategoryID      *int       `db:"category_id" json:"category_id"`
+	Status          string     `db:"status" json:"status"`
+	Priority        string     `db:"priority" json:"priority" validate:"omitempty,oneof=low normal high urgent"`
+	EstimateMinutes *int       `db:"estimate_minutes" json:"estimate_minutes" validate:"omitempty,min=0"`
+	DueDate         *time.Time `db:"due_date" json:"due_date"`
+	OverdueAt       *time.Time `db:"overdue_at" json:"overdue_at"`
+	SeriesID        *int       `db:"series_id" json:"series_id"`
+	OccurrenceAt    *time.Time `db:"occurrence_at" json:"occurrence_at"`
+	Language        string     `db:"language" json:"language" validate:"omitempty,max=63"`
+	Version         int        `db:"version" json:"version"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+	Labels          []Label    `db:"-" json:"labels"`
+	LabelIDs        []int      `db:"-" json:"label_ids,omitempty"`
+	Assignees       []int      `db:"-" json:"assignees"`
+}
+
+// taskColumns are the columns read into a Task. Queries name them instead of
+// selecting * so that only searches read the search vectors.
+var taskColumns = []string{
+	"id", "title", "description", "user_id", "created_by", "project_id", "parent_id", "category_id", "status",
+	"priority", "estimate_minutes", "due_date", "overdue_at", "series_id", "occurrence_at", "language", "version",
+	"created_at", "updated_at",
+}
+
+// taskSelect is the select list of the task columns
+var taskSelect = strings.Join(taskColumns, ", ")
+
+// qualifiedTaskSelect returns the select list of the task columns of the
+// table aliased as alias
+func qualifiedTaskSelect(alias string) string {
+	columns := make([]string, len(taskColumns))
+	for i, column := range taskColumns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
 }
 
 // Category represents a task category
@@ -36,44 +97,118 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-// Create adds a new task to the database
+// Create adds a new task to the database together with its initial status
+// history entry, the labels in task.LabelIDs and the assignees in
+// task.Assignees. Everything is saved in one transaction, so a failure
+// leaves no partly created task. ErrLabelNotVisible and ErrInvalidAssignee
+// are returned for labels and assignees the task cannot have.
 func (r *TaskRepository) Create(task *Task) error {
 	query := `
-		INSERT INTO tasks (title, description, user_id, category_id, status, due_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
-		RETURNING id, created_at, updated_at
+		INSERT INTO tasks (title, description, user_id, created_by, project_id, parent_id, category_id, status, priority, estimate_minutes, due_date, language, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING id, version, created_at, updated_at
 	`
 	
-	return r.db.QueryRowx(
+	task.DueDate = inUTC(task.DueDate)
+	if task.Language == "" {
+		task.Language = DefaultLanguage
+	}
+	
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	err = tx.QueryRowx(
 		query,
 		task.Title,
 		task.Description,
 		task.UserID,
+		task.CreatedBy,
+		task.ProjectID,
+		task.ParentID,
 		task.CategoryID,
 		task.Status,
+		task.Priority,
+		task.EstimateMinutes,
 		task.DueDate,
-	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+		task.Language,
+	).Scan(&task.ID, &task.Version, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	
+	_, err = tx.Exec(
+		`INSERT INTO task_status_history (task_id, from_status, to_status, changed_by, changed_at)
+		 VALUES ($1, NULL, $2, $3, NOW())`,
+		task.ID, task.Status, task.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	
+	if task.LabelIDs != nil {
+		if err := setTaskLabels(tx, task.ID, task.UserID, task.LabelIDs); err != nil {
+			return err
+		}
+	}
+	if err := setAssignees(tx, task, task.Assignees, task.UserID); err != nil {
+		return err
+	}
+	
+	// Reminders go to the assignees
+	if err := scheduleReminders(tx, "task_id = $1", task.ID); err != nil {
+		return err
+	}
+	
+	return tx.Commit()
 }
 
-// Update modifies an existing task
+// Update modifies an existing task. task.Version must hold the version that
+// was read; ErrVersionConflict is returned if the row has changed since. The
+// overdue flag is cleared when the due date changes or the task is closed.
 func (r *TaskRepository) Update(task *Task) error {
 	query := `
 		UPDATE tasks
-		SET title = $1, description = $2, category_id = $3, status = $4, due_date = $5, updated_at = NOW()
-		WHERE id = $6 AND user_id = $7
-		RETURNING updated_at
+		SET title = $1, description = $2, project_id = $3, parent_id = $4, category_id = $5, status = $6,
+			priority = $7, estimate_minutes = $8, due_date = $9, language = $14, version = version + 1, updated_at = NOW(),
+			overdue_at = CASE WHEN due_date IS DISTINCT FROM $9 OR $6 = ANY($13) THEN NULL ELSE overdue_at END
+		WHERE id = $10 AND user_id = $11 AND version = $12
+		RETURNING version, created_at, updated_at, overdue_at
 	`
 	
-	return r.db.QueryRowx(
+	task.DueDate = inUTC(task.DueDate)
+	err := r.db.QueryRowx(
 		query,
 		task.Title,
 		task.Description,
+		task.ProjectID,
+		task.ParentID,
 		task.CategoryID,
 		task.Status,
+		task.Priority,
+		task.EstimateMinutes,
 		task.DueDate,
 		task.ID,
 		task.UserID,
-	).Scan(&task.UpdatedAt)
+		task.Version,
+		closedStatuses,
+		task.Language,
+	).Scan(&task.Version, &task.CreatedAt, &task.UpdatedAt, &task.OverdueAt)
+	if err == sql.ErrNoRows {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// inUTC returns t in UTC; due dates are stored without a time zone, as UTC
+func inUTC(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
 }
 
 // Delete removes a task by ID
@@ -85,22 +220,16 @@ func (r *TaskRepository) Delete(id, userID int) error {
 // FindByID finds a task by ID
 func (r *TaskRepository) FindByID(id int) (*Task, error) {
 	task := &Task{}
-	err := r.db.Get(task, "SELECT * FROM tasks WHERE id = $1", id)
+	err := r.db.Get(task, "SELECT "+taskSelect+" FROM tasks WHERE id = $1", id)
 	return task, err
 }
 
-// ListByUser returns all tasks for a specific user
-func (r *TaskRepository) ListByUser(userID int) ([]Task, error) {
-	var tasks []Task
-	err := r.db.Select(&tasks, "SELECT * FROM tasks WHERE user_id = $1 ORDER BY due_date ASC", userID)
-	return tasks, err
-}
-
-// ListAllTasks returns all tasks (admin only)
-func (r *TaskRepository) ListAll() ([]Task, error) {
-	var tasks []Task
-	err := r.db.Select(&tasks, "SELECT * FROM tasks ORDER BY due_date ASC")
-	return tasks, err
+// ValidLanguage reports whether name is a text search configuration known
+// to the database
+func (r *TaskRepository) ValidLanguage(name string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = $1)", name)
+	return exists, err
 }
 
 // CategoryRepository handles database operations for categories
@@ -124,10 +253,17 @@ func (r *CategoryRepository) Create(category *Category) error {
 	return r.db.QueryRowx(query, category.Name).Scan(&category.ID, &category.CreatedAt)
 }
 
+// FindByID finds a category by ID
+func (r *CategoryRepository) FindByID(id int) (*Category, error) {
+	category := &Category{}
+	err := r.db.Get(category, "SELECT id, name, created_at FROM categories WHERE id = $1", id)
+	return category, err
+}
+
 // List returns all categories
 func (r *CategoryRepository) List() ([]Category, error) {
 	var categories []Category
-	err := r.db.Select(&categories, "SELECT * FROM categories ORDER BY name")
+	err := r.db.Select(&categories, "SELECT id, name, created_at FROM categories ORDER BY name")
 	return categories, err
 }
 
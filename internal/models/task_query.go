@@ -0,0 +1,281 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultTaskPageSize is used when the client does not ask for a limit
+	DefaultTaskPageSize = 20
+	// MaxTaskPageSize caps the number of tasks returned in one page
+	MaxTaskPageSize = 100
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// taskSortColumns maps allowed sort keys to SQL expressions. NULL due dates
+// sort last so the keyset comparison never has to deal with NULLs.
+var taskSortColumns = map[string]string{
+	"due_date":   "COALESCE(due_date, 'infinity'::timestamp)",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+}
+
+// IsValidTaskSort reports whether sort is an allowed sort key
+func IsValidTaskSort(sort string) bool {
+	_, ok := taskSortColumns[sort]
+	return ok
+}
+
+// TaskFilter describes which tasks to list and in what order
+type TaskFilter struct {
+	VisibleTo     *int // restrict to tasks this user can see; nil for all tasks
+	AllProjects   bool // with VisibleTo, the user can also see every project task
+	ProjectID     *int
+	CreatedBy     *int
+	AssigneeID    *int
+	WatcherID     *int
+	Status        string
+	CategoryID    *int
+	Priorities    []string
+	LabelIDs      []int // tasks must carry every one of these labels
+	EstimateMin   *int
+	EstimateMax   *int
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Query         string
+	Sort          string
+	Desc          bool
+	Limit         int
+	Cursor        string
+}
+
+// TaskPage is one page of a task listing
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// taskCursor is the keyset position encoded into next_cursor
+type taskCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// encodeTaskCursor builds an opaque cursor pointing after task
+func encodeTaskCursor(sort string, task *Task) string {
+	cursor := taskCursor{Sort: sort, ID: task.ID}
+	switch sort {
+	case "title":
+		cursor.Value = task.Title
+	case "created_at":
+		cursor.Value = task.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = task.UpdatedAt.Format(time.RFC3339Nano)
+	case "due_date":
+		if task.DueDate != nil {
+			cursor.Value = task.DueDate.Format(time.RFC3339Nano)
+		}
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor parses a cursor and returns the value to compare against
+func decodeTaskCursor(raw, sort string) (interface{}, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	if sort == "title" {
+		return cursor.Value, cursor.ID, nil
+	}
+
+	// A due_date cursor with no value came from a task without a due date
+	if cursor.Value == "" && sort == "due_date" {
+		return "infinity", cursor.ID, nil
+	}
+
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return value, cursor.ID, nil
+}
+
+// whereBuilder accumulates SQL conditions with numbered placeholders
+type whereBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition; each "?" in cond is replaced by the next placeholder
+func (b *whereBuilder) add(cond string, args ...interface{}) {
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(b.args)), 1)
+	}
+	b.conditions = append(b.conditions, cond)
+}
+
+// clause returns the WHERE clause, or an empty string if there are no conditions
+func (b *whereBuilder) clause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
+
+// applyTaskFilter adds the non-pagination conditions of filter to b
+func applyTaskFilter(b *whereBuilder, filter *TaskFilter) {
+	if filter.VisibleTo != nil && filter.AllProjects {
+		b.add(`(project_id IS NOT NULL
+			OR user_id = ?
+			OR id IN (SELECT task_id FROM task_assignees WHERE user_id = ?))`,
+			*filter.VisibleTo, *filter.VisibleTo)
+	} else if filter.VisibleTo != nil {
+		b.add(`((project_id IS NULL AND user_id = ?)
+			OR project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)
+			OR id IN (SELECT task_id FROM task_assignees WHERE user_id = ?))`,
+			*filter.VisibleTo, *filter.VisibleTo, *filter.VisibleTo)
+	}
+	if filter.ProjectID != nil {
+		b.add("project_id = ?", *filter.ProjectID)
+	}
+	if filter.CreatedBy != nil {
+		b.add("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.AssigneeID != nil {
+		b.add("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", *filter.AssigneeID)
+	}
+	if filter.WatcherID != nil {
+		b.add("id IN (SELECT task_id FROM task_watchers WHERE user_id = ?)", *filter.WatcherID)
+	}
+	if filter.Status != "" {
+		b.add("status = ?", filter.Status)
+	}
+	if filter.CategoryID != nil {
+		b.add("category_id = ?", *filter.CategoryID)
+	}
+	if len(filter.Priorities) > 0 {
+		b.add("priority = ANY(?)", pq.Array(filter.Priorities))
+	}
+	if len(filter.LabelIDs) > 0 {
+		labelIDs := uniqueInts(filter.LabelIDs)
+		b.add(`id IN (
+			SELECT task_id FROM task_labels WHERE label_id = ANY(?)
+			GROUP BY task_id HAVING COUNT(*) = ?
+		)`, pq.Array(labelIDs), len(labelIDs))
+	}
+	if filter.EstimateMin != nil {
+		b.add("estimate_minutes >= ?", *filter.EstimateMin)
+	}
+	if filter.EstimateMax != nil {
+		b.add("estimate_minutes <= ?", *filter.EstimateMax)
+	}
+	if filter.DueAfter != nil {
+		b.add("due_date >= ?", *filter.DueAfter)
+	}
+	if filter.DueBefore != nil {
+		b.add("due_date < ?", *filter.DueBefore)
+	}
+	if filter.CreatedAfter != nil {
+		b.add("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		b.add("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		b.add("updated_at >= ?", *filter.UpdatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		b.add("updated_at < ?", *filter.UpdatedBefore)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		b.add("(title ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+}
+
+// List returns a filtered, sorted page of tasks using keyset pagination
+func (r *TaskRepository) List(filter TaskFilter) (*TaskPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = "due_date"
+	}
+	sortExpr, ok := taskSortColumns[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field %q", filter.Sort)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTaskPageSize
+	}
+	if filter.Limit > MaxTaskPageSize {
+		filter.Limit = MaxTaskPageSize
+	}
+
+	// Count matches before the cursor condition is applied
+	where := &whereBuilder{}
+	applyTaskFilter(where, &filter)
+
+	page := &TaskPage{Tasks: []Task{}}
+	if err := r.db.Get(&page.Total, "SELECT COUNT(*) FROM tasks"+where.clause(), where.args...); err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		value, id, err := decodeTaskCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		where.add(fmt.Sprintf("(%s, id) %s (?, ?)", sortExpr, comparison), value, id)
+	}
+
+	// Fetch one extra row to know whether another page exists
+	query := fmt.Sprintf(
+		"SELECT %s FROM tasks%s ORDER BY %s %s, id %s LIMIT %d",
+		taskSelect, where.clause(), sortExpr, direction, direction, filter.Limit+1,
+	)
+	if err := r.db.Select(&page.Tasks, query, where.args...); err != nil {
+		return nil, err
+	}
+
+	if len(page.Tasks) > filter.Limit {
+		page.Tasks = page.Tasks[:filter.Limit]
+		page.NextCursor = encodeTaskCursor(filter.Sort, &page.Tasks[len(page.Tasks)-1])
+	}
+
+	return page, nil
+}
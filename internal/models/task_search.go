@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Highlight delimiters passed to ts_headline. They are swapped for <mark>
+// tags after the task text has been HTML-escaped.
+const (
+	highlightStart = "[[hl]]"
+	highlightStop  = "[[/hl]]"
+)
+
+// ts_headline options for the whole title and a snippet of the description
+const (
+	titleHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", HighlightAll=true`
+	headlineOptions      = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", ` +
+		`MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// TaskSearch describes a full-text search over tasks. Filter narrows the
+// tasks searched; its Query, Sort and Cursor are ignored.
+type TaskSearch struct {
+	Filter   TaskFilter
+	Query    string
+	Language string // text search configuration the query is stemmed with
+	Offset   int
+}
+
+// TaskSearchResult is a task matching a search, with its rank and the
+// matching text highlighted in HTML with <mark> tags
+type TaskSearchResult struct {
+	Task
+	Rank           float64 `db:"rank" json:"rank"`
+	TitleHighlight string  `db:"title_highlight" json:"title_highlight"`
+	Snippet        string  `db:"snippet" json:"snippet"`
+	CategoryName   *string `db:"category_name" json:"category_name"`
+}
+
+// TaskSearchPage is one page of search results, best match first
+type TaskSearchPage struct {
+	Results []TaskSearchResult `json:"results"`
+	Total   int                `json:"total"`
+}
+
+// Search returns the tasks whose title or description match search.Query,
+// stemmed in search.Language, or whose category name contains its words.
+// Title matches rank above description matches, which rank above category
+// matches.
+func (r *TaskRepository) Search(search TaskSearch) (*TaskSearchPage, error) {
+	filter := search.Filter
+	filter.Query = ""
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTaskPageSize
+	}
+	if filter.Limit > MaxTaskPageSize {
+		filter.Limit = MaxTaskPageSize
+	}
+	if search.Language == "" {
+		search.Language = DefaultLanguage
+	}
+
+	where := &whereBuilder{}
+	applyTaskFilter(where, &filter)
+	where.add(
+		`(search_vector @@ websearch_to_tsquery(?::regconfig, ?)
+			OR category_id IN (SELECT id FROM categories WHERE search_vector @@ websearch_to_tsquery('simple', ?)))`,
+		search.Language, search.Query, search.Query,
+	)
+
+	page := &TaskSearchPage{Results: []TaskSearchResult{}}
+	if err := r.db.Get(&page.Total, "SELECT COUNT(*) FROM tasks"+where.clause(), where.args...); err != nil {
+		return nil, err
+	}
+
+	// The matching tasks are filtered in a subquery so that the filter's
+	// unqualified columns stay unambiguous next to categories
+	n := len(where.args)
+	args := append(where.args, search.Language, search.Query, search.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s,
+			ts_rank(t.search_vector, q.query) + 0.1 * ts_rank(COALESCE(c.search_vector, ''::tsvector), q.simple) AS rank,
+			ts_headline(t.language, t.title, q.query, '%s') AS title_highlight,
+			ts_headline(t.language, t.description, q.query, '%s') AS snippet,
+			c.name AS category_name
+		FROM (SELECT %s, search_vector FROM tasks%s) t
+		CROSS JOIN (
+			SELECT websearch_to_tsquery($%d::regconfig, $%d::text) AS query,
+				websearch_to_tsquery('simple', $%d::text) AS simple
+		) q
+		LEFT JOIN categories c ON c.id = t.category_id
+		ORDER BY rank DESC, t.id DESC
+		LIMIT %d OFFSET $%d
+	`, qualifiedTaskSelect("t"), titleHeadlineOptions, headlineOptions, taskSelect, where.clause(), n+1, n+2, n+2, filter.Limit, n+3)
+	if err := r.db.Select(&page.Results, query, args...); err != nil {
+		return nil, err
+	}
+
+	for i := range page.Results {
+		page.Results[i].TitleHighlight = markHighlights(page.Results[i].TitleHighlight)
+		page.Results[i].Snippet = markHighlights(page.Results[i].Snippet)
+	}
+	return page, nil
+}
+
+// markHighlights escapes headline text for HTML and turns the highlight
+// delimiters into <mark> tags
+func markHighlights(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again; the whole token family is revoked
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// RefreshToken is a stored refresh token; the plaintext is never stored
+type RefreshToken struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// TokenRepository handles refresh tokens and the access token denylist
+type TokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewTokenRepository creates a new token repository
+func NewTokenRepository(db *sqlx.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// randomToken returns n random bytes encoded for use in URLs and headers
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// insertRefreshToken stores a new token in family and returns its plaintext
+func insertRefreshToken(q sqlx.Queryer, userID int, familyID string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	var id int
+	err = q.QueryRowx(`
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
+	`, userID, familyID, hashToken(token), time.Now().UTC().Add(ttl)).Scan(&id)
+	return token, err
+}
+
+// CreateRefreshToken starts a new token family for a fresh login
+func (r *TokenRepository) CreateRefreshToken(userID int, ttl time.Duration) (string, error) {
+	familyBytes := make([]byte, 16)
+	if _, err := rand.Read(familyBytes); err != nil {
+		return "", err
+	}
+
+	return insertRefreshToken(r.db, userID, hex.EncodeToString(familyBytes), ttl)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same
+// family and returns the new token and its user. Presenting a token that was
+// already exchanged revokes every token in its family.
+func (r *TokenRepository) RotateRefreshToken(token string, ttl time.Duration) (string, int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback()
+
+	var current RefreshToken
+	err = tx.Get(&current, "SELECT * FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE", hashToken(token))
+	if err == sql.ErrNoRows {
+		return "", 0, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if current.UsedAt != nil {
+		// Someone is replaying a rotated token: assume it leaked and end the session
+		if _, err := tx.Exec(
+			"UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL",
+			current.FamilyID,
+		); err != nil {
+			return "", 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return "", 0, err
+		}
+		return "", 0, ErrRefreshTokenReused
+	}
+
+	if current.RevokedAt != nil || time.Now().UTC().After(current.ExpiresAt) {
+		return "", 0, ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", current.ID); err != nil {
+		return "", 0, err
+	}
+
+	next, err := insertRefreshToken(tx, current.UserID, current.FamilyID, ttl)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return next, current.UserID, tx.Commit()
+}
+
+// RevokeRefreshToken revokes the family of a refresh token owned by userID
+func (r *TokenRepository) RevokeRefreshToken(token string, userID int) error {
+	result, err := r.db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		)
+	`, hashToken(token), userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// RevokeAllRefreshTokens revokes every refresh token of a user
+func (r *TokenRepository) RevokeAllRefreshTokens(userID int) error {
+	_, err := r.db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+		userID,
+	)
+	return err
+}
+
+// RevokeAccessToken adds an access token's JWT ID to the denylist until it
+// would have expired anyway
+func (r *TokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	_, err := r.db.Exec(
+		"INSERT INTO revoked_tokens (jti, expires_at, revoked_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING",
+		jti, expiresAt.UTC(),
+	)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether a JWT ID is on the denylist
+func (r *TokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.Get(&revoked, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti)
+	return revoked, err
+}
+
+// PurgeExpired deletes refresh tokens and denylist entries that have expired
+func (r *TokenRepository) PurgeExpired(now time.Time) (int64, error) {
+	var purged int64
+	for _, query := range []string{
+		"DELETE FROM refresh_tokens WHERE expires_at < $1",
+		"DELETE FROM revoked_tokens WHERE expires_at < $1",
+	} {
+		result, err := r.db.Exec(query, now.UTC())
+		if err != nil {
+			return purged, err
+		}
+		n, _ := result.RowsAffected()
+		purged += n
+	}
+	return purged, nil
+}
@@ -2,21 +2,35 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrDuplicateUser is returned when a username or email is already taken
+	ErrDuplicateUser = errors.New("username or email already taken")
+	// ErrSoleOwner is returned when deleting a user who is the only owner of
+	// a project that has other members
+	ErrSoleOwner = errors.New("user is the only owner of a shared project")
+)
+
 // User represents a system user
 type User struct {
-	ID           int       `db:"id" json:"id"`
-	Username     string    `db:"username" json:"username" validate:"required,min=3,max=50"`
-	Email        string    `db:"email" json:"email" validate:"required,email"`
-	PasswordHash string    `db:"password_hash" json:"-"`
-	Role         string    `db:"role" json:"role"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ID              int        `db:"id" json:"id"`
+	Username        string     `db:"username" json:"username" validate:"required,min=3,max=50"`
+	Email           string     `db:"email" json:"email" validate:"required,email"`
+	PasswordHash    string     `db:"password_hash" json:"-"`
+	Role            string     `db:"role" json:"role"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"`
+	DeactivatedAt   *time.Time `db:"deactivated_at" json:"deactivated_at"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 // LoginRequest for user authentication
@@ -71,8 +85,8 @@ func (r *UserRepository) Create(user *User, password string) error {
 // FindByUsername finds a user by username
 func (r *UserRepository) FindByUsername(username string) (*User, error) {
     user := &User{}
-    err := r.db.QueryRow("SELECT id, username, email, password_hash, role FROM users WHERE username = $1", username).
-        Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role)
+    err := r.db.QueryRow("SELECT id, username, email, password_hash, role, email_verified_at, deactivated_at FROM users WHERE username = $1", username).
+        Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.EmailVerifiedAt, &user.DeactivatedAt)
     
     if err == sql.ErrNoRows {
         // Không tìm thấy username, trả về nil, nil thay vì lỗi
@@ -97,12 +111,257 @@ func (r *UserRepository) FindByID(id int) (*User, error) {
 // List returns all users
 func (r *UserRepository) List() ([]User, error) {
 	var users []User
-	err := r.db.Select(&users, "SELECT id, username, email, role, created_at, updated_at FROM users")
+	err := r.db.Select(&users, "SELECT id, username, email, role, email_verified_at, deactivated_at, created_at, updated_at FROM users")
 	return users, err
 }
 
-// CheckPassword verifies a user's password
+// dummyPasswordHash is compared against when there is no real hash, so that
+// failing takes as long for unknown users as for wrong passwords
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// CheckPassword verifies a user's password. A nil user or one without a
+// password never matches.
 func (r *UserRepository) CheckPassword(user *User, password string) bool {
+	if user == nil || user.PasswordHash == "" {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+// FindByEmail finds a user by email address, returning nil if there is none
+func (r *UserRepository) FindByEmail(email string) (*User, error) {
+	user := &User{}
+	err := r.db.Get(user, "SELECT * FROM users WHERE LOWER(email) = LOWER($1) ORDER BY id LIMIT 1", email)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// MarkEmailVerified records that a user has confirmed their email address
+func (r *UserRepository) MarkEmailVerified(userID int) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1",
+		userID,
+	)
+	return err
+}
+
+// SetPassword replaces a user's password
+func (r *UserRepository) SetPassword(userID int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(
+		"UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2",
+		string(hashedPassword), userID,
+	)
+	return err
+}
+
+// CreateExternal adds a user who signs in through an identity provider and
+// has no password
+func (r *UserRepository) CreateExternal(user *User) error {
+	query := `
+		INSERT INTO users (username, email, password_hash, role, email_verified_at, created_at, updated_at)
+		VALUES ($1, $2, '', $3, $4, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRowx(query, user.Username, user.Email, user.Role, user.EmailVerifiedAt).
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+}
+
+// ClearPassword removes a user's password so they can only sign in through
+// an identity provider or after a password reset
+func (r *UserRepository) ClearPassword(userID int) error {
+	_, err := r.db.Exec("UPDATE users SET password_hash = '', updated_at = NOW() WHERE id = $1", userID)
+	return err
+}
+
+// SetRole changes a user's role
+func (r *UserRepository) SetRole(userID int, role string) error {
+	_, err := r.db.Exec("UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2", role, userID)
+	return err
+}
+
+// UpdateProfile saves a user's username and email. Changing the email marks
+// it unverified and invalidates outstanding emailed tokens, which were sent to
+// the old address.
+func (r *UserRepository) UpdateProfile(user *User) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldEmail string
+	if err := tx.Get(&oldEmail, "SELECT email FROM users WHERE id = $1 FOR UPDATE", user.ID); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(oldEmail, user.Email) {
+		user.EmailVerifiedAt = nil
+		_, err := tx.Exec("UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowx(`
+		UPDATE users SET username = $1, email = $2, email_verified_at = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at
+	`, user.Username, user.Email, user.EmailVerifiedAt, user.ID).Scan(&user.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrDuplicateUser
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsActive reports whether a user exists and is not deactivated
+func (r *UserRepository) IsActive(userID int) (bool, error) {
+	var active bool
+	err := r.db.Get(&active,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deactivated_at IS NULL)",
+		userID,
+	)
+	return active, err
+}
+
+// SetDeactivated deactivates or reactivates a user. It returns sql.ErrNoRows
+// if there is no such user.
+func (r *UserRepository) SetDeactivated(userID int, deactivated bool) error {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET deactivated_at = CASE WHEN $2 THEN COALESCE(deactivated_at, NOW()) END, updated_at = NOW()
+		WHERE id = $1
+	`, userID, deactivated)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes a user and everything personal to them. With reassignTo
+// their tasks, series and project ownerships pass to that user. Otherwise
+// personal tasks and series are deleted, projects only they belong to are
+// deleted, and their project tasks pass to another owner of the project;
+// being the only owner of a project with other members fails with
+// ErrSoleOwner.
+func (r *UserRepository) Delete(userID int, reassignTo *int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if reassignTo != nil {
+		if err := reassignOwnership(tx, userID, *reassignTo); err != nil {
+			return err
+		}
+	} else {
+		if err := releaseProjects(tx, userID); err != nil {
+			return err
+		}
+	}
+
+	// Personal tasks, series, tokens and memberships cascade
+	result, err := tx.Exec("DELETE FROM users WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// reassignOwnership hands everything userID owns to another user
+func reassignOwnership(tx *sqlx.Tx, userID, reassignTo int) error {
+	if _, err := tx.Exec("UPDATE tasks SET user_id = $2 WHERE user_id = $1", userID, reassignTo); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE task_series SET user_id = $2 WHERE user_id = $1", userID, reassignTo); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO project_members (project_id, user_id, role, created_at)
+		SELECT project_id, $2, 'owner', NOW() FROM project_members
+		WHERE user_id = $1 AND role = 'owner'
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = 'owner'
+	`, userID, reassignTo)
+	return err
+}
+
+// releaseProjects prepares userID's projects for the user's deletion: their
+// solo projects are deleted and their project tasks pass to the longest
+// standing other owner
+func releaseProjects(tx *sqlx.Tx, userID int) error {
+	var soleOwner bool
+	err := tx.Get(&soleOwner, `
+		SELECT EXISTS(
+			SELECT 1 FROM project_members m
+			WHERE m.user_id = $1 AND m.role = 'owner'
+			AND NOT EXISTS (
+				SELECT 1 FROM project_members o
+				WHERE o.project_id = m.project_id AND o.user_id <> $1 AND o.role = 'owner'
+			)
+			AND EXISTS (
+				SELECT 1 FROM project_members o
+				WHERE o.project_id = m.project_id AND o.user_id <> $1
+			)
+		)
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if soleOwner {
+		return ErrSoleOwner
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM projects p
+		WHERE EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.id AND m.user_id = $1)
+		AND NOT EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.id AND m.user_id <> $1)
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tasks t SET user_id = o.user_id
+		FROM (
+			SELECT DISTINCT ON (project_id) project_id, user_id FROM project_members
+			WHERE role = 'owner' AND user_id <> $1
+			ORDER BY project_id, created_at, user_id
+		) o
+		WHERE t.user_id = $1 AND t.project_id = o.project_id
+	`, userID)
+	return err
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Purposes of emailed user tokens
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// ErrInvalidUserToken is returned for unknown, used or expired emailed tokens
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// UserTokenRepository handles single-use tokens sent to users by email
+type UserTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserTokenRepository creates a new user token repository
+func NewUserTokenRepository(db *sqlx.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create issues a token for purpose and returns its plaintext. Earlier unused
+// tokens for the same purpose stop working.
+func (r *UserTokenRepository) Create(userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userID, purpose,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, userID, purpose, hashToken(token), time.Now().UTC().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+// Consume marks a token as used and returns its user. It fails with
+// ErrInvalidUserToken if the token is unknown, used, expired or meant for
+// another purpose.
+func (r *UserTokenRepository) Consume(token, purpose string) (int, error) {
+	var userID int
+	err := r.db.Get(&userID, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING user_id
+	`, hashToken(token), purpose, time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidUserToken
+	}
+	return userID, err
+}
+
+// PurgeExpired deletes tokens that can no longer be used
+func (r *UserTokenRepository) PurgeExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM user_tokens WHERE expires_at < $1", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,311 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/yourusername/Task_Management/internal/events"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSecretPrefix starts every generated webhook secret
+const WebhookSecretPrefix = "whsec_"
+
+// Webhook posts the events of a project, or of its owner's tasks, to a URL
+type Webhook struct {
+	ID        int            `db:"id" json:"id"`
+	UserID    int            `db:"user_id" json:"user_id"`
+	ProjectID *int           `db:"project_id" json:"project_id"`
+	URL       string         `db:"url" json:"url"`
+	Secret    string         `db:"secret" json:"-"`
+	Events    pq.StringArray `db:"events" json:"events"`
+	Active    bool           `db:"active" json:"active"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// JSON is a JSON column passed through unchanged
+type JSON []byte
+
+// Scan copies the column, which the driver may reuse
+func (j *JSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	case nil:
+		*j = nil
+	default:
+		return errors.New("unsupported JSON column type")
+	}
+	return nil
+}
+
+// Value stores j as a JSON column
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return []byte(j), nil
+}
+
+// MarshalJSON embeds j as is
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// WebhookDelivery is an event queued for, or delivered to, a webhook
+type WebhookDelivery struct {
+	ID             int64      `db:"id" json:"id"`
+	WebhookID      int        `db:"webhook_id" json:"webhook_id"`
+	EventID        *int64     `db:"event_id" json:"event_id"`
+	EventType      string     `db:"event_type" json:"event_type"`
+	Payload        JSON       `db:"payload" json:"payload"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttemptAt  *time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `db:"last_attempt_at" json:"last_attempt_at"`
+	ResponseStatus *int       `db:"response_status" json:"response_status"`
+	LastError      *string    `db:"last_error" json:"last_error"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at"`
+}
+
+// DueDelivery is a claimed delivery with where to send it
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// WebhookRepository handles webhooks and their delivery outbox
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create stores a new webhook, generating a secret if it has none
+func (r *WebhookRepository) Create(webhook *Webhook) error {
+	if webhook.Secret == "" {
+		secret, err := randomToken(24)
+		if err != nil {
+			return err
+		}
+		webhook.Secret = WebhookSecretPrefix + secret
+	}
+
+	return r.db.QueryRowx(`
+		INSERT INTO webhooks (user_id, project_id, url, secret, events, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, webhook.UserID, webhook.ProjectID, webhook.URL, webhook.Secret, webhook.Events, webhook.Active).
+		Scan(&webhook.ID, &webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+// FindByID finds a webhook by ID
+func (r *WebhookRepository) FindByID(id int) (*Webhook, error) {
+	webhook := &Webhook{}
+	err := r.db.Get(webhook, "SELECT * FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListByUser returns the webhooks a user created
+func (r *WebhookRepository) ListByUser(userID int) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := r.db.Select(&webhooks, "SELECT * FROM webhooks WHERE user_id = $1 ORDER BY id", userID)
+	return webhooks, err
+}
+
+// Update saves a webhook's URL, event filter and active flag, and its secret
+// if one is set
+func (r *WebhookRepository) Update(webhook *Webhook) error {
+	return r.db.QueryRowx(`
+		UPDATE webhooks
+		SET url = $1, events = $2, active = $3, secret = COALESCE(NULLIF($4, ''), secret), updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at
+	`, webhook.URL, webhook.Events, webhook.Active, webhook.Secret, webhook.ID).Scan(&webhook.UpdatedAt)
+}
+
+// Delete removes a webhook and its deliveries
+func (r *WebhookRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM webhooks WHERE id = $1", id)
+	return err
+}
+
+// Enqueue queues payload for every active webhook subscribed to event. A
+// project webhook stops receiving events when its creator leaves the
+// project. It returns how many deliveries were queued.
+func (r *WebhookRepository) Enqueue(ctx context.Context, event *events.Event, payload []byte) (int64, error) {
+	assignees := make(pq.Int64Array, len(event.AssigneeIDs))
+	for i, id := range event.AssigneeIDs {
+		assignees[i] = int64(id)
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at, created_at)
+		SELECT w.id, $1::bigint, $2, $3::jsonb, NOW(), NOW()
+		FROM webhooks w
+		WHERE w.active
+			AND (cardinality(w.events) = 0 OR $2 = ANY(w.events))
+			AND CASE
+				WHEN w.project_id IS NOT NULL THEN w.project_id = $4 AND EXISTS (
+					SELECT 1 FROM project_members m WHERE m.project_id = w.project_id AND m.user_id = w.user_id
+				)
+				ELSE ($4::int IS NULL AND w.user_id = $5)
+					OR w.user_id = ANY($6)
+					OR w.user_id IN (SELECT user_id FROM task_assignees WHERE task_id = $7)
+			END
+	`, event.ID, event.Type, JSON(payload), event.ProjectID, event.OwnerID, assignees, event.TaskID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// EnqueueForUser queues payload for a user's active personal webhooks
+// subscribed to eventType. It returns how many deliveries were queued.
+func (r *WebhookRepository) EnqueueForUser(ctx context.Context, userID int, eventType string, payload []byte) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at, created_at)
+		SELECT w.id, $2, $3::jsonb, NOW(), NOW()
+		FROM webhooks w
+		WHERE w.user_id = $1 AND w.project_id IS NULL AND w.active
+			AND (cardinality(w.events) = 0 OR $2 = ANY(w.events))
+	`, userID, eventType, JSON(payload))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// EnqueueFor queues payload for a single webhook, whatever its filter
+func (r *WebhookRepository) EnqueueFor(webhookID int, eventType string, payload []byte) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := r.db.Get(delivery, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING *
+	`, webhookID, eventType, JSON(payload))
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (r *WebhookRepository) ListDeliveries(webhookID, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := r.db.Select(&deliveries,
+		"SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit,
+	)
+	return deliveries, err
+}
+
+// ListByStatus returns the most recent deliveries of every webhook with
+// status, newest first
+func (r *WebhookRepository) ListByStatus(status string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := r.db.Select(&deliveries,
+		"SELECT * FROM webhook_deliveries WHERE status = $1 ORDER BY id DESC LIMIT $2",
+		status, limit,
+	)
+	return deliveries, err
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts. It returns
+// sql.ErrNoRows if there is no such delivery.
+func (r *WebhookRepository) Redeliver(id int64) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := r.db.Get(delivery, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = NULL,
+			response_status = NULL, delivered_at = NULL
+		WHERE id = $1
+		RETURNING *
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ClaimDue picks up to limit pending deliveries that are due and hides them
+// from other dispatchers until lease has passed, so that each is attempted
+// by one dispatcher at a time. Deliveries of inactive webhooks wait until
+// the webhook is active again.
+func (r *WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]DueDelivery, error) {
+	deliveries := []DueDelivery{}
+	err := r.db.Select(&deliveries, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.*, w.url, w.secret
+	`, limit, lease.Seconds())
+	return deliveries, err
+}
+
+// MarkDelivered records a successful attempt
+func (r *WebhookRepository) MarkDelivered(id int64, responseStatus int) error {
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_attempt_at = NOW(), delivered_at = NOW(),
+			next_attempt_at = NULL, response_status = $1, last_error = NULL
+		WHERE id = $2
+	`, responseStatus, id)
+	return err
+}
+
+// MarkFailed records a failed attempt to be retried after retryIn.
+// responseStatus is nil if no response was received.
+func (r *WebhookRepository) MarkFailed(id int64, responseStatus *int, reason string, retryIn time.Duration) error {
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, last_attempt_at = NOW(), next_attempt_at = NOW() + make_interval(secs => $1),
+			response_status = $2, last_error = $3
+		WHERE id = $4
+	`, retryIn.Seconds(), responseStatus, reason, id)
+	return err
+}
+
+// MarkDead records a failed final attempt and dead-letters the delivery
+func (r *WebhookRepository) MarkDead(id int64, responseStatus *int, reason string) error {
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'dead', attempts = attempts + 1, last_attempt_at = NOW(), next_attempt_at = NULL,
+			response_status = $1, last_error = $2
+		WHERE id = $3
+	`, responseStatus, reason, id)
+	return err
+}
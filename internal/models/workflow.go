@@ -0,0 +1,228 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Built-in task statuses
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+	StatusCancelled  = "cancelled"
+)
+
+// ErrInvalidStatus is returned when a status is not part of the workflow
+var ErrInvalidStatus = errors.New("invalid status")
+
+// ErrInvalidTransition is returned when the workflow forbids a status change
+var ErrInvalidTransition = errors.New("status transition not allowed")
+
+// defaultTransitions is the workflow every category starts from
+var defaultTransitions = map[string][]string{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusPending, StatusBlocked, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusPending, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusPending, StatusInProgress}, // reopen
+	StatusCancelled:  {StatusPending},                   // reopen
+}
+
+// Workflow is the set of statuses and transitions that apply to a task
+type Workflow struct {
+	Statuses    []string            `json:"statuses"`
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// DefaultWorkflow returns a copy of the built-in workflow
+func DefaultWorkflow() *Workflow {
+	w := &Workflow{Transitions: make(map[string][]string)}
+	for _, status := range []string{StatusPending, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled} {
+		w.Statuses = append(w.Statuses, status)
+		w.Transitions[status] = append([]string{}, defaultTransitions[status]...)
+	}
+	return w
+}
+
+// HasStatus reports whether status is part of the workflow
+func (w *Workflow) HasStatus(status string) bool {
+	for _, s := range w.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// addStatus adds a status if it is not already present
+func (w *Workflow) addStatus(status string) {
+	if !w.HasStatus(status) {
+		w.Statuses = append(w.Statuses, status)
+	}
+}
+
+// addTransition adds an allowed transition if it is not already present
+func (w *Workflow) addTransition(from, to string) {
+	for _, s := range w.Transitions[from] {
+		if s == to {
+			return
+		}
+	}
+	w.Transitions[from] = append(w.Transitions[from], to)
+}
+
+// ValidateTransition checks that a task may move from one status to another.
+// Tasks whose current status predates the workflow may move to any status.
+func (w *Workflow) ValidateTransition(from, to string) error {
+	if !w.HasStatus(to) {
+		return ErrInvalidStatus
+	}
+	if from == to || !w.HasStatus(from) {
+		return nil
+	}
+	for _, s := range w.Transitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	return ErrInvalidTransition
+}
+
+// StatusTransition is an allowed transition configured for a category
+type StatusTransition struct {
+	ID         int       `db:"id" json:"id"`
+	CategoryID int       `db:"category_id" json:"category_id"`
+	FromStatus string    `db:"from_status" json:"from_status" validate:"required,max=20"`
+	ToStatus   string    `db:"to_status" json:"to_status" validate:"required,max=20"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// StatusHistoryEntry records a single status change of a task
+type StatusHistoryEntry struct {
+	ID         int       `db:"id" json:"id"`
+	TaskID     int       `db:"task_id" json:"task_id"`
+	FromStatus *string   `db:"from_status" json:"from_status"`
+	ToStatus   string    `db:"to_status" json:"to_status"`
+	ChangedBy  *int      `db:"changed_by" json:"changed_by"`
+	ChangedAt  time.Time `db:"changed_at" json:"changed_at"`
+}
+
+// WorkflowRepository handles workflow configuration and status history
+type WorkflowRepository struct {
+	db *sqlx.DB
+}
+
+// NewWorkflowRepository creates a new workflow repository
+func NewWorkflowRepository(db *sqlx.DB) *WorkflowRepository {
+	return &WorkflowRepository{db: db}
+}
+
+// ForCategory returns the default workflow extended with the category's
+// extra statuses and transitions
+func (r *WorkflowRepository) ForCategory(categoryID *int) (*Workflow, error) {
+	workflow := DefaultWorkflow()
+	if categoryID == nil {
+		return workflow, nil
+	}
+
+	var statuses []string
+	if err := r.db.Select(&statuses, "SELECT name FROM category_statuses WHERE category_id = $1 ORDER BY id", *categoryID); err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		workflow.addStatus(status)
+	}
+
+	transitions, err := r.ListTransitions(*categoryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transitions {
+		workflow.addTransition(t.FromStatus, t.ToStatus)
+	}
+
+	return workflow, nil
+}
+
+// AddStatus adds an extra status to a category's workflow
+func (r *WorkflowRepository) AddStatus(categoryID int, name string) error {
+	_, err := r.db.Exec(
+		"INSERT INTO category_statuses (category_id, name, created_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING",
+		categoryID, name,
+	)
+	return err
+}
+
+// RemoveStatus removes an extra status and any transitions that use it
+func (r *WorkflowRepository) RemoveStatus(categoryID int, name string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM category_statuses WHERE category_id = $1 AND name = $2", categoryID, name); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"DELETE FROM category_status_transitions WHERE category_id = $1 AND (from_status = $2 OR to_status = $2)",
+		categoryID, name,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListTransitions returns the extra transitions configured for a category
+func (r *WorkflowRepository) ListTransitions(categoryID int) ([]StatusTransition, error) {
+	transitions := []StatusTransition{}
+	err := r.db.Select(&transitions, "SELECT * FROM category_status_transitions WHERE category_id = $1 ORDER BY id", categoryID)
+	return transitions, err
+}
+
+// AddTransition allows an extra transition in a category's workflow
+func (r *WorkflowRepository) AddTransition(t *StatusTransition) error {
+	query := `
+		INSERT INTO category_status_transitions (category_id, from_status, to_status, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (category_id, from_status, to_status) DO UPDATE SET from_status = EXCLUDED.from_status
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRowx(query, t.CategoryID, t.FromStatus, t.ToStatus).Scan(&t.ID, &t.CreatedAt)
+}
+
+// DeleteTransition removes an extra transition from a category's workflow
+func (r *WorkflowRepository) DeleteTransition(categoryID, id int) error {
+	result, err := r.db.Exec("DELETE FROM category_status_transitions WHERE id = $1 AND category_id = $2", id, categoryID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordTransition appends a status change to a task's history
+func (r *WorkflowRepository) RecordTransition(taskID int, from *string, to string, actorID int) error {
+	_, err := r.db.Exec(
+		`INSERT INTO task_status_history (task_id, from_status, to_status, changed_by, changed_at)
+		 VALUES ($1, $2, $3, $4, NOW())`,
+		taskID, from, to, actorID,
+	)
+	return err
+}
+
+// History returns a task's status changes, oldest first
+func (r *WorkflowRepository) History(taskID int) ([]StatusHistoryEntry, error) {
+	history := []StatusHistoryEntry{}
+	err := r.db.Select(&history, "SELECT * FROM task_status_history WHERE task_id = $1 ORDER BY changed_at, id", taskID)
+	return history, err
+}
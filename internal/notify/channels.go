@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/yourusername/Task_Management/internal/mail"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/webhooks"
+)
+
+// EmailChannel mails notifications to users with a verified address
+type EmailChannel struct {
+	mailer     mail.Mailer
+	appBaseURL string
+}
+
+// NewEmailChannel creates an email channel linking to tasks in the web app
+// at appBaseURL
+func NewEmailChannel(mailer mail.Mailer, appBaseURL string) *EmailChannel {
+	return &EmailChannel{mailer: mailer, appBaseURL: appBaseURL}
+}
+
+// Send mails notification to user
+func (e *EmailChannel) Send(ctx context.Context, user *models.User, notification *models.Notification) error {
+	// Unverified addresses may not belong to the user
+	if user.EmailVerifiedAt == nil {
+		return nil
+	}
+
+	body := notification.Body
+	if notification.TaskID != nil {
+		body += "\n\n" + e.appBaseURL + "/tasks/" + strconv.Itoa(*notification.TaskID)
+	}
+	return e.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: notification.Title,
+		Body:    body,
+	})
+}
+
+// WebhookChannel posts notifications to the user's personal webhooks that
+// are subscribed to webhooks.NotificationEvent
+type WebhookChannel struct {
+	outbox *webhooks.Outbox
+}
+
+// NewWebhookChannel creates a webhook channel queueing deliveries in outbox
+func NewWebhookChannel(outbox *webhooks.Outbox) *WebhookChannel {
+	return &WebhookChannel{outbox: outbox}
+}
+
+// Send queues notification for the user's webhooks
+func (w *WebhookChannel) Send(ctx context.Context, user *models.User, notification *models.Notification) error {
+	_, err := w.outbox.SendToUser(ctx, user.ID, webhooks.NotificationEvent, notification)
+	return err
+}
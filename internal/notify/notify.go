@@ -0,0 +1,100 @@
+// Package notify sends notifications through channels besides the app, such
+// as email and webhooks. Each delivery is a job, so failed deliveries are
+// retried with backoff.
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yourusername/Task_Management/internal/jobs"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// Channel delivers a notification to its user
+type Channel interface {
+	Send(ctx context.Context, user *models.User, notification *models.Notification) error
+}
+
+// deliverPayload is the payload of a delivery job
+type deliverPayload struct {
+	NotificationID int64  `json:"notification_id"`
+	Channel        string `json:"channel"`
+}
+
+// deliverJob sends one notification through one channel
+var deliverJob = jobs.NewKind[deliverPayload]("notification.deliver")
+
+// Notifier queues and performs deliveries of notifications through their
+// channels
+type Notifier struct {
+	queue            *jobs.Queue
+	notificationRepo *models.NotificationRepository
+	userRepo         *models.UserRepository
+	channels         map[string]Channel
+}
+
+// NewNotifier creates a notifier without channels
+func NewNotifier(queue *jobs.Queue, notificationRepo *models.NotificationRepository, userRepo *models.UserRepository) *Notifier {
+	return &Notifier{
+		queue:            queue,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		channels:         make(map[string]Channel),
+	}
+}
+
+// AddChannel makes ch deliver notifications for the named channel
+func (n *Notifier) AddChannel(name string, ch Channel) {
+	n.channels[name] = ch
+}
+
+// Register registers the delivery job with r so that a worker performs
+// deliveries
+func (n *Notifier) Register(r *jobs.Registry) {
+	deliverJob.Handle(r, n.deliver)
+}
+
+// Dispatch queues a delivery of each notification through each of its
+// channels
+func (n *Notifier) Dispatch(ctx context.Context, notifications []models.Notification) error {
+	for _, notification := range notifications {
+		for _, channel := range notification.Channels {
+			_, err := deliverJob.Enqueue(ctx, n.queue, deliverPayload{NotificationID: notification.ID, Channel: channel}, jobs.Options{
+				UniqueKey: fmt.Sprintf("notification:%d:%s", notification.ID, channel),
+			})
+			if err != nil && err != models.ErrDuplicateJob {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deliver sends a notification through a channel. Notifications deleted
+// meanwhile and deactivated users are skipped.
+func (n *Notifier) deliver(ctx context.Context, payload deliverPayload, job *models.Job) error {
+	ch, ok := n.channels[payload.Channel]
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("unknown notification channel %q", payload.Channel))
+	}
+
+	notification, err := n.notificationRepo.FindByID(payload.NotificationID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := n.userRepo.FindByID(notification.UserID)
+	if err == sql.ErrNoRows || (err == nil && user.DeactivatedAt != nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return ch.Send(ctx, user, notification)
+}
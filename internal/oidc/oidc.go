@@ -0,0 +1,405 @@
+// Package oidc implements the client side of the OpenID Connect
+// authorization code flow with PKCE
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/utils"
+)
+
+const (
+	// clockSkew is how far the provider's clock may be off from ours
+	clockSkew = time.Minute
+	// discoveryTTL is how long discovery documents and keys are cached
+	discoveryTTL = time.Hour
+	// keyRefreshInterval limits refetching keys when a token names an unknown kid
+	keyRefreshInterval = time.Minute
+)
+
+// ErrInvalidIDToken is returned when an ID token fails verification
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Claims are the ID token claims used to sign a user in
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	// Raw holds every claim, for role mapping
+	Raw map[string]interface{}
+}
+
+// discovery is the subset of the provider metadata the login flow needs
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is a configured identity provider. Its metadata and keys are
+// fetched on first use and cached.
+type Provider struct {
+	config config.OIDCProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *discovery
+	metaFetched time.Time
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+	now         func() time.Time
+}
+
+// NewProvider creates a provider from its configuration
+func NewProvider(cfg config.OIDCProviderConfig) *Provider {
+	return &Provider{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// NewProviders creates the configured providers keyed by name
+func NewProviders(cfgs []config.OIDCProviderConfig) map[string]*Provider {
+	providers := make(map[string]*Provider, len(cfgs))
+	for _, cfg := range cfgs {
+		providers[cfg.Name] = NewProvider(cfg)
+	}
+	return providers
+}
+
+// Config returns the provider's configuration
+func (p *Provider) Config() config.OIDCProviderConfig {
+	return p.config
+}
+
+// RandomString returns n random bytes encoded for use in URLs, for state,
+// nonce and PKCE verifier values
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge derives the S256 PKCE challenge from verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL to send the browser to
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified claims of
+// the ID token. nonce must be the value sent with the authorization request.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s: %s %s", resp.Status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verify(ctx, meta, token.IDToken, nonce)
+}
+
+// rawClaims lets jwt parse claims without validating them; verify checks
+// them with clock skew allowed
+type rawClaims map[string]interface{}
+
+// Valid implements jwt.Claims
+func (rawClaims) Valid() error { return nil }
+
+// verify checks an ID token's signature, issuer, audience, lifetime and
+// nonce and extracts its claims
+func (p *Provider) verify(ctx context.Context, meta *discovery, idToken, nonce string) (*Claims, error) {
+	raw := rawClaims{}
+	_, err := jwt.ParseWithClaims(idToken, &raw, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm must fit the key so an attacker cannot pick a weaker one
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, ErrInvalidIDToken
+			}
+		case *ecdsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+				return nil, ErrInvalidIDToken
+			}
+		case ed25519.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+				return nil, ErrInvalidIDToken
+			}
+		default:
+			return nil, ErrInvalidIDToken
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	now := p.now()
+	if iss, _ := raw["iss"].(string); iss != meta.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !audienceContains(raw["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidIDToken)
+	}
+	if azp, ok := raw["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", ErrInvalidIDToken, azp)
+	}
+	exp, ok := raw["exp"].(float64)
+	if !ok || now.Add(-clockSkew).Unix() >= int64(exp) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if iat, ok := raw["iat"].(float64); ok && now.Add(clockSkew).Unix() < int64(iat) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+	if got, _ := raw["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.Name, _ = raw["name"].(string)
+	claims.PreferredUsername, _ = raw["preferred_username"].(string)
+	// Some providers send email_verified as a string
+	switch verified := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// audienceContains reports whether an aud claim, a string or a list,
+// includes clientID
+func audienceContains(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MapRole returns the role for a user with the given claims: the role of
+// the first mapping whose value appears in the role claim, otherwise the
+// default role
+func (p *Provider) MapRole(claims *Claims) string {
+	if p.config.RoleClaim == "" {
+		return p.config.DefaultRole
+	}
+
+	var values []string
+	switch claim := claims.Raw[p.config.RoleClaim].(type) {
+	case string:
+		values = strings.Fields(claim)
+	case []interface{}:
+		for _, v := range claim {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	for _, mapping := range p.config.RoleMap {
+		for _, v := range values {
+			if v == mapping.Value {
+				return mapping.Role
+			}
+		}
+	}
+	return p.config.DefaultRole
+}
+
+// discover returns the provider metadata, fetching it when the cache is stale
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil && p.now().Sub(p.metaFetched) < discoveryTTL {
+		return p.meta, nil
+	}
+
+	meta := &discovery{}
+	if err := p.getJSON(ctx, p.config.Issuer+"/.well-known/openid-configuration", meta); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", p.config.Issuer, err)
+	}
+	if meta.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, expected %q", meta.Issuer, p.config.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.meta, p.metaFetched = meta, p.now()
+	return meta, nil
+}
+
+// key returns the provider's public key with ID kid. Keys are refetched when
+// the cache is stale or kid is unknown, so provider key rotation is picked
+// up without a restart.
+func (p *Provider) key(ctx context.Context, meta *discovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := lookupKey(p.keys, kid); ok && p.now().Sub(p.keysFetched) < discoveryTTL {
+		return key, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keysFetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set utils.JWKSet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching provider keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	p.keys, p.keysFetched = keys, p.now()
+
+	key, ok := lookupKey(keys, kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds the key with ID kid. Tokens without a kid are accepted
+// only when the provider publishes a single key.
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// getJSON fetches url and decodes the JSON response into v
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,147 @@
+// Package rbac resolves roles to the permissions they grant
+package rbac
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// Permissions that roles can be granted. The names match the permissions
+// table.
+const (
+	TaskCreate       = "task:create"
+	TaskReadOwn      = "task:read:own"
+	TaskUpdateOwn    = "task:update:own"
+	TaskDeleteOwn    = "task:delete:own"
+	TaskReadAny      = "task:read:any"
+	TaskUpdateAny    = "task:update:any"
+	TaskDeleteAny    = "task:delete:any"
+	TaskReassignAny  = "task:reassign:any"
+	ProjectReadAny   = "project:read:any"
+	ProjectManageAny = "project:manage:any"
+	SeriesManageAny  = "series:manage:any"
+	CategoryManage   = "category:manage"
+	LabelManage      = "label:manage"
+	UserReadAny      = "user:read:any"
+	UserManage       = "user:manage"
+	MFAManage        = "mfa:manage"
+	RoleManage       = "role:manage"
+	WebhookManage    = "webhook:manage"
+)
+
+// AdminRole is the built-in role that must always keep RoleManage
+const AdminRole = "admin"
+
+// Scopes limit what a personal access token may do on behalf of its user
+const (
+	// ScopeTasksRead allows reading tasks and projects
+	ScopeTasksRead = "tasks:read"
+	// ScopeTasksWrite additionally allows changing tasks
+	ScopeTasksWrite = "tasks:write"
+	// ScopeAdmin allows everything the user's role does
+	ScopeAdmin = "admin"
+)
+
+// scopePermissions are the permissions each scope lets a token keep. Admin
+// keeps all of them.
+var scopePermissions = map[string][]string{
+	ScopeTasksRead: {TaskReadOwn, TaskReadAny, ProjectReadAny},
+	ScopeTasksWrite: {
+		TaskReadOwn, TaskReadAny, ProjectReadAny, TaskCreate,
+		TaskUpdateOwn, TaskUpdateAny, TaskDeleteOwn, TaskDeleteAny,
+		TaskReassignAny, SeriesManageAny,
+	},
+}
+
+// ValidScope reports whether scope is a known token scope
+func ValidScope(scope string) bool {
+	return scope == ScopeAdmin || scopePermissions[scope] != nil
+}
+
+// Set is the permissions of a role
+type Set map[string]bool
+
+// Has reports whether the set contains all of perms
+func (s Set) Has(perms ...string) bool {
+	for _, perm := range perms {
+		if !s[perm] {
+			return false
+		}
+	}
+	return true
+}
+
+// Scoped returns the permissions of s that scopes allow
+func (s Set) Scoped(scopes []string) Set {
+	scoped := make(Set)
+	for _, scope := range scopes {
+		if scope == ScopeAdmin {
+			return s
+		}
+		for _, perm := range scopePermissions[scope] {
+			if s[perm] {
+				scoped[perm] = true
+			}
+		}
+	}
+	return scoped
+}
+
+// Policy maps roles to permissions. It caches the role table for ttl so that
+// permission checks do not query the database on every request; changes made
+// through another instance show up once the cache expires.
+type Policy struct {
+	repo *models.RBACRepository
+	ttl  time.Duration
+
+	mu       sync.RWMutex
+	grants   map[string]Set
+	loadedAt time.Time
+}
+
+// NewPolicy creates a policy backed by repo
+func NewPolicy(repo *models.RBACRepository, ttl time.Duration) *Policy {
+	return &Policy{repo: repo, ttl: ttl}
+}
+
+// Permissions returns the permissions granted to role. Unknown roles have
+// none.
+func (p *Policy) Permissions(role string) (Set, error) {
+	p.mu.RLock()
+	if p.grants != nil && time.Since(p.loadedAt) < p.ttl {
+		perms := p.grants[role]
+		p.mu.RUnlock()
+		return perms, nil
+	}
+	p.mu.RUnlock()
+
+	grants, err := p.repo.Grants()
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make(map[string]Set, len(grants))
+	for name, perms := range grants {
+		set := make(Set, len(perms))
+		for _, perm := range perms {
+			set[perm] = true
+		}
+		sets[name] = set
+	}
+
+	p.mu.Lock()
+	p.grants = sets
+	p.loadedAt = time.Now()
+	p.mu.Unlock()
+
+	return sets[role], nil
+}
+
+// Invalidate drops the cache so that the next lookup sees role changes
+func (p *Policy) Invalidate() {
+	p.mu.Lock()
+	p.grants = nil
+	p.mu.Unlock()
+}
@@ -0,0 +1,405 @@
+// Package recurrence implements the subset of iCalendar recurrence rules
+// (RFC 5545 RRULE) used by recurring tasks: FREQ, INTERVAL, BYDAY,
+// BYMONTHDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base unit a rule repeats in
+type Frequency string
+
+// Supported frequencies
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxEmptyPeriods stops the search when a rule has not matched for this many
+// consecutive periods, so rules that can never match do not loop forever
+const maxEmptyPeriods = 1000
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var untilLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is zero when the
+// entry applies to every matching weekday in the period.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed recurrence rule anchored at DTStart
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+	DTStart    time.Time
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func Parse(value string, dtstart time.Time) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("rrule is empty")
+	}
+
+	rule := &Rule{Interval: 1, DTStart: dtstart}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq := Frequency(strings.ToUpper(val))
+			switch freq {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = freq
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val, dtstart.Location())
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// parseUntil parses an UNTIL value as a UTC date-time, local date-time or date
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			if strings.HasSuffix(value, "Z") {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+			}
+			if layout == "20060102" {
+				// A bare date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+// parseWeekdayNum parses a BYDAY entry such as "MO", "2TU" or "-1FR"
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	day, ok := weekdayCodes[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	wd := WeekdayNum{Day: day}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// validate checks combinations the parser cannot catch part by part
+func (r *Rule) validate() error {
+	if r.Freq == "" {
+		return errors.New("rrule requires FREQ")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return errors.New("rrule cannot have both COUNT and UNTIL")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return errors.New("numbered BYDAY is only allowed with FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	return nil
+}
+
+// String returns the rule in RRULE value form
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			code := strings.ToUpper(wd.Day.String()[:2])
+			if wd.N != 0 {
+				code = strconv.Itoa(wd.N) + code
+			}
+			days[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// First returns the first occurrence at or after DTStart
+func (r *Rule) First() (time.Time, bool) {
+	return r.After(r.DTStart.Add(-time.Nanosecond))
+}
+
+// After returns the first occurrence strictly after t. The second result is
+// false when the rule has no further occurrences.
+func (r *Rule) After(t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.each(func(occurrence time.Time) bool {
+		if occurrence.After(t) {
+			next, found = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Remaining counts the occurrences at or after t. It is only meaningful for
+// rules bounded by COUNT.
+func (r *Rule) Remaining(t time.Time) int {
+	n := 0
+	r.each(func(occurrence time.Time) bool {
+		if !occurrence.Before(t) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// each calls fn for every occurrence in order until fn returns false or the
+// rule is exhausted. Unbounded rules must be stopped by fn.
+func (r *Rule) each(fn func(time.Time) bool) {
+	index, empty := 0, 0
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		candidates := r.expand(period)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, candidate := range candidates {
+			if candidate.Before(r.DTStart) {
+				continue
+			}
+			if r.Until != nil && candidate.After(*r.Until) {
+				return
+			}
+			index++
+			if r.Count > 0 && index > r.Count {
+				return
+			}
+			if !fn(candidate) {
+				return
+			}
+		}
+	}
+}
+
+// expand returns the sorted candidate occurrences of the nth period
+func (r *Rule) expand(period int) []time.Time {
+	start := r.DTStart
+	loc := start.Location()
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, loc)
+	}
+
+	var candidates []time.Time
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, period*r.Interval)
+		if r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		// Weeks start on Monday (WKST=MO)
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*period*r.Interval)
+		if len(r.ByDay) == 0 {
+			candidates = append(candidates, monday.AddDate(0, 0, (int(start.Weekday())+6)%7))
+		}
+		for _, wd := range r.ByDay {
+			day := monday.AddDate(0, 0, (int(wd.Day)+6)%7)
+			candidates = append(candidates, at(day.Year(), day.Month(), day.Day()))
+		}
+	case Monthly:
+		first := at(start.Year(), start.Month()+time.Month(period*r.Interval), 1)
+		candidates = r.monthDays(first.Year(), first.Month(), at)
+	case Yearly:
+		candidates = r.monthDays(start.Year()+period*r.Interval, start.Month(), at)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return dedupe(candidates)
+}
+
+// monthDays expands BYMONTHDAY/BYDAY within a month, falling back to
+// DTStart's day of the month
+func (r *Rule) monthDays(year int, month time.Month, at func(int, time.Month, int) time.Time) []time.Time {
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = daysInMonth + md + 1
+			}
+			if day < 1 || day > daysInMonth {
+				continue
+			}
+			if t := at(year, month, day); r.matchesWeekday(t) {
+				days = append(days, t)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []time.Time
+			for day := 1; day <= daysInMonth; day++ {
+				if t := at(year, month, day); t.Weekday() == wd.Day {
+					matches = append(matches, t)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	default:
+		if day := r.DTStart.Day(); day <= daysInMonth {
+			days = append(days, at(year, month, day))
+		}
+	}
+	return days
+}
+
+// matchesWeekday reports whether t falls on one of the BYDAY weekdays
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if t.Weekday() == wd.Day {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay reports whether t falls on one of the BYMONTHDAY days
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || (md < 0 && daysInMonth+md+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe removes adjacent equal times from a sorted slice
+func dedupe(times []time.Time) []time.Time {
+	if len(times) < 2 {
+		return times
+	}
+	result := times[:1]
+	for _, t := range times[1:] {
+		if !t.Equal(result[len(result)-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/storage"
+)
+
+// AttachmentSweeper removes stored contents that no attachment refers to,
+// such as the files of deleted tasks and projects
+type AttachmentSweeper struct {
+	attachRepo *models.AttachmentRepository
+	store      storage.Storage
+	interval   time.Duration
+}
+
+// NewAttachmentSweeper creates a sweeper that runs every interval
+func NewAttachmentSweeper(attachRepo *models.AttachmentRepository, store storage.Storage, interval time.Duration) *AttachmentSweeper {
+	return &AttachmentSweeper{
+		attachRepo: attachRepo,
+		store:      store,
+		interval:   interval,
+	}
+}
+
+// Run sweeps orphaned attachment contents until ctx is cancelled
+func (s *AttachmentSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick deletes the contents of every orphaned blob
+func (s *AttachmentSweeper) tick(ctx context.Context) {
+	removed := 0
+	err := s.attachRepo.DeleteOrphanedBlobs(func(sha256 string) error {
+		removed++
+		return s.store.Delete(ctx, models.BlobKey(sha256))
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to remove orphaned attachments")
+		return
+	}
+	if removed > 0 {
+		logrus.WithField("count", removed).Info("Removed orphaned attachment contents")
+	}
+}
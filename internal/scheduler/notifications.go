@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/notify"
+)
+
+// notificationBatch is how many reminders or overdue tasks are handled per
+// query
+const notificationBatch = 100
+
+// NotificationSweeper sends due reminders and flags overdue tasks, creating
+// in-app notifications and queueing their other deliveries
+type NotificationSweeper struct {
+	reminderRepo     *models.ReminderRepository
+	notificationRepo *models.NotificationRepository
+	notifier         *notify.Notifier
+	interval         time.Duration
+}
+
+// NewNotificationSweeper creates a sweeper that runs every interval
+func NewNotificationSweeper(reminderRepo *models.ReminderRepository, notificationRepo *models.NotificationRepository, notifier *notify.Notifier, interval time.Duration) *NotificationSweeper {
+	return &NotificationSweeper{
+		reminderRepo:     reminderRepo,
+		notificationRepo: notificationRepo,
+		notifier:         notifier,
+		interval:         interval,
+	}
+}
+
+// Run sends reminders and flags overdue tasks until ctx is cancelled
+func (s *NotificationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick handles everything that is currently due
+func (s *NotificationSweeper) tick(ctx context.Context) {
+	s.sweep(ctx, "reminder", s.reminderRepo.SendDue)
+	s.sweep(ctx, "overdue", s.notificationRepo.FlagOverdue)
+}
+
+// sweep creates notifications with next, a batch at a time, until it
+// returns a short batch, and dispatches them
+func (s *NotificationSweeper) sweep(ctx context.Context, kind string, next func(limit int) ([]models.Notification, error)) {
+	log := logrus.WithField("kind", kind)
+	sent := 0
+	for ctx.Err() == nil {
+		// The notifications are saved before their deliveries are queued;
+		// deliveries are lost if the process dies in between
+		notifications, err := next(notificationBatch)
+		if err != nil {
+			log.WithError(err).Error("Failed to create notifications")
+			break
+		}
+		if err := s.notifier.Dispatch(ctx, notifications); err != nil {
+			log.WithError(err).Error("Failed to queue notification deliveries")
+		}
+		sent += len(notifications)
+
+		if len(notifications) < notificationBatch {
+			break
+		}
+	}
+	if sent > 0 {
+		log.WithField("count", sent).Info("Sent notifications")
+	}
+}
@@ -0,0 +1,107 @@
+// Package scheduler runs periodic background work inside the API process.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// RecurringScheduler materializes due occurrences of recurring task series
+// and announces each new task on the broker
+type RecurringScheduler struct {
+	seriesRepo  *models.SeriesRepository
+	taskRepo    *models.TaskRepository
+	projectRepo *models.ProjectRepository
+	assignRepo  *models.AssignmentRepository
+	broker      *events.Broker
+	interval    time.Duration
+}
+
+// NewRecurringScheduler creates a scheduler that checks for due series every
+// interval. broker may be nil, in which case no events are published.
+func NewRecurringScheduler(seriesRepo *models.SeriesRepository, taskRepo *models.TaskRepository, projectRepo *models.ProjectRepository, assignRepo *models.AssignmentRepository, broker *events.Broker, interval time.Duration) *RecurringScheduler {
+	return &RecurringScheduler{
+		seriesRepo:  seriesRepo,
+		taskRepo:    taskRepo,
+		projectRepo: projectRepo,
+		assignRepo:  assignRepo,
+		broker:      broker,
+		interval:    interval,
+	}
+}
+
+// Run checks for due series until ctx is cancelled
+func (s *RecurringScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick materializes every occurrence that is currently due
+func (s *RecurringScheduler) tick(ctx context.Context) {
+	created, err := s.seriesRepo.MaterializeDue(time.Now().UTC())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to materialize recurring tasks")
+	}
+	if len(created) > 0 {
+		logrus.WithField("count", len(created)).Info("Created recurring task occurrences")
+	}
+
+	for _, taskID := range created {
+		if err := s.publishCreated(ctx, taskID); err != nil {
+			logrus.WithError(err).WithField("task_id", taskID).Error("Failed to publish recurring task occurrence")
+		}
+	}
+}
+
+// publishCreated announces a new occurrence as task.created, the same event
+// creating a task through the API publishes. The scheduler acts for nobody,
+// so the event has no actor.
+func (s *RecurringScheduler) publishCreated(ctx context.Context, taskID int) error {
+	if s.broker == nil {
+		return nil
+	}
+
+	task, err := s.taskRepo.FindByID(taskID)
+	if err != nil {
+		return err
+	}
+	tasks := []models.Task{*task}
+	if err := s.assignRepo.AttachAssignees(tasks); err != nil {
+		return err
+	}
+	task = &tasks[0]
+
+	audience, err := s.projectRepo.TaskAudience(task)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	return s.broker.Publish(context.WithoutCancel(ctx), &events.Event{
+		Type:        events.TaskCreated,
+		TaskID:      task.ID,
+		ProjectID:   task.ProjectID,
+		OwnerID:     task.UserID,
+		MemberIDs:   audience.MemberIDs,
+		AssigneeIDs: audience.AssigneeIDs,
+		Data:        payload,
+	})
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// TokenPurger deletes expired refresh tokens, denylist entries, emailed
+// user tokens and MFA challenges, and failed-login counters that are older
+// than the throttle window
+type TokenPurger struct {
+	tokenRepo      *models.TokenRepository
+	userTokenRepo  *models.UserTokenRepository
+	mfaRepo        *models.MFARepository
+	throttleRepo   *models.LoginThrottleRepository
+	throttleWindow time.Duration
+	interval       time.Duration
+}
+
+// NewTokenPurger creates a purger that runs every interval
+func NewTokenPurger(tokenRepo *models.TokenRepository, userTokenRepo *models.UserTokenRepository, mfaRepo *models.MFARepository, throttleRepo *models.LoginThrottleRepository, throttleWindow, interval time.Duration) *TokenPurger {
+	return &TokenPurger{
+		tokenRepo:      tokenRepo,
+		userTokenRepo:  userTokenRepo,
+		mfaRepo:        mfaRepo,
+		throttleRepo:   throttleRepo,
+		throttleWindow: throttleWindow,
+		interval:       interval,
+	}
+}
+
+// Run purges expired tokens until ctx is cancelled
+func (p *TokenPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.tick()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick deletes everything that has expired
+func (p *TokenPurger) tick() {
+	purged, err := p.tokenRepo.PurgeExpired(time.Now())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to purge expired tokens")
+		return
+	}
+
+	userTokens, err := p.userTokenRepo.PurgeExpired(time.Now())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to purge expired user tokens")
+		return
+	}
+	purged += userTokens
+
+	challenges, err := p.mfaRepo.PurgeExpiredChallenges(time.Now())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to purge expired MFA challenges")
+		return
+	}
+	purged += challenges
+
+	counters, err := p.throttleRepo.PurgeStale(p.throttleWindow, time.Now())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to purge failed-login counters")
+		return
+	}
+	purged += counters
+	if purged > 0 {
+		logrus.WithField("count", purged).Info("Purged expired tokens")
+	}
+}
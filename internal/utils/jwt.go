@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 	
@@ -13,34 +15,42 @@ type JWTClaims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Unverified is set while the user's email address is unconfirmed
+	Unverified bool `json:"unverified,omitempty"`
+	// MFAPending is set while the user's role requires MFA the user has not set up
+	MFAPending bool `json:"mfa_pending,omitempty"`
 	jwt.StandardClaims
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(user *models.User, secret string, expiration time.Duration) (string, error) {
+// GenerateToken creates a new JWT token for a user. Each token gets a
+// unique ID (jti) so it can be revoked before it expires. With mfaPending
+// the token only grants access to MFA enrollment.
+func GenerateToken(user *models.User, keys *KeySet, expiration time.Duration, mfaPending bool) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	
 	claims := JWTClaims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		// Tokens are reissued on refresh, so verifying takes effect then
+		Unverified: user.EmailVerifiedAt == nil,
+		MFAPending: mfaPending,
 		StandardClaims: jwt.StandardClaims{
+			Id:        hex.EncodeToString(jti),
 			ExpiresAt: time.Now().Add(expiration).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	}
 	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
 // ValidateToken verifies and parses a JWT token
-func ValidateToken(tokenString, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
+func ValidateToken(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keys.Keyfunc)
 	
 	if err != nil {
 		return nil, err
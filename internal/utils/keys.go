@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/yourusername/Task_Management/internal/config"
+)
+
+// SigningKey is an asymmetric key used to sign and verify access tokens
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Private is nil for keys that are only used for verification
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	// ActiveFrom is when the key starts signing new tokens
+	ActiveFrom time.Time
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 and EC keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet holds the keys access tokens are signed and verified with. Keys
+// take over signing at their ActiveFrom time; a key that has been
+// superseded keeps verifying for one token lifetime so tokens it signed stay
+// valid until they expire. Without asymmetric keys, tokens are signed with
+// the shared HMAC secret.
+type KeySet struct {
+	keys       []*SigningKey // sorted by ActiveFrom
+	hmacSecret []byte
+	tokenTTL   time.Duration
+	now        func() time.Time
+}
+
+// NewKeySet creates a key set from already loaded keys
+func NewKeySet(keys []*SigningKey, hmacSecret string, tokenTTL time.Duration) *KeySet {
+	sorted := append([]*SigningKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom)
+	})
+
+	return &KeySet{
+		keys:       sorted,
+		hmacSecret: []byte(hmacSecret),
+		tokenTTL:   tokenTTL,
+		now:        time.Now,
+	}
+}
+
+// LoadKeySet reads the PEM files referenced by cfg.SigningKeys
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	keys := make([]*SigningKey, 0, len(cfg.SigningKeys))
+	seen := make(map[string]bool)
+	for _, kc := range cfg.SigningKeys {
+		if seen[kc.ID] {
+			return nil, fmt.Errorf("duplicate signing key id %q", kc.ID)
+		}
+		seen[kc.ID] = true
+
+		key, err := loadSigningKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", kc.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 && cfg.JWTSecret == "" {
+		return nil, errors.New("either a JWT secret or signing keys are required")
+	}
+
+	return NewKeySet(keys, cfg.JWTSecret, cfg.TokenExpiration), nil
+}
+
+// loadSigningKey parses a PEM private key, or a public key for keys that
+// only verify, and picks the algorithm from the key type
+func loadSigningKey(kc config.SigningKeyConfig) (*SigningKey, error) {
+	data, err := os.ReadFile(kc.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kc.ID, ActiveFrom: kc.ActiveFrom}
+
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, private, &private.PublicKey
+		return key, nil
+	}
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, private, private.(ed25519.PrivateKey).Public()
+		return key, nil
+	}
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		key.Method, key.Public = jwt.SigningMethodRS256, public
+		return key, nil
+	}
+	if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		key.Method, key.Public = jwt.SigningMethodEdDSA, public
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported key: expected an RSA or Ed25519 key in PEM format")
+}
+
+// signingKey returns the key that signs new tokens now, or nil to fall back
+// to HMAC
+func (ks *KeySet) signingKey() *SigningKey {
+	now := ks.now()
+	var current *SigningKey
+	for _, key := range ks.keys {
+		if key.Private != nil && !key.ActiveFrom.After(now) {
+			current = key
+		}
+	}
+	return current
+}
+
+// Sign creates a signed token for claims
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.signingKey()
+	if key == nil {
+		if len(ks.hmacSecret) == 0 {
+			return "", errors.New("no signing key is active")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc returns the key that verifies token, rejecting unknown key IDs,
+// algorithm mismatches and keys outside their validity window
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(ks.hmacSecret) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ks.hmacSecret, nil
+	}
+
+	for i, key := range ks.keys {
+		if key.ID != kid {
+			continue
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		if !ks.verifies(i) {
+			return nil, errors.New("signing key is not valid")
+		}
+		return key.Public, nil
+	}
+
+	return nil, errors.New("unknown signing key")
+}
+
+// verifies reports whether keys[i] may currently verify tokens: it must have
+// become active, and if a later key has taken over, the tokens it signed
+// must not all have expired yet
+func (ks *KeySet) verifies(i int) bool {
+	now := ks.now()
+	if ks.keys[i].ActiveFrom.After(now) {
+		return false
+	}
+
+	for _, later := range ks.keys[i+1:] {
+		if later.Private != nil && !later.ActiveFrom.After(now) {
+			return now.Before(later.ActiveFrom.Add(ks.tokenTTL))
+		}
+	}
+	return true
+}
+
+// JWKS returns the public keys that verify tokens now or will soon sign
+// them, so that verifiers can fetch a key before it is first used
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for i, key := range ks.keys {
+		if !key.ActiveFrom.After(ks.now()) && !ks.verifies(i) {
+			continue
+		}
+
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// PublicKey decodes an RSA, EC or Ed25519 JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported OKP key %q", k.Curve)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// dispatchBatch is how many deliveries are attempted at once
+const dispatchBatch = 20
+
+// maxResponseBody is how much of a response is read so that its connection
+// can be reused. Response bodies are never stored.
+const maxResponseBody = 4096
+
+// Dispatcher attempts due deliveries from the outbox, retrying failures with
+// exponential backoff until they are delivered or dead-lettered. Several
+// dispatchers, in one process or many, may share the outbox.
+type Dispatcher struct {
+	repo   *models.WebhookRepository
+	cfg    config.WebhookConfig
+	client *http.Client
+	wake   chan struct{}
+}
+
+// NewDispatcher creates a dispatcher with the retry schedule in cfg
+func NewDispatcher(repo *models.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	// Every connection is checked against the address it actually dials, so
+	// that a host re-resolving to an internal address is still refused.
+	// Proxies are not used, since the check would only see the proxy.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDial(cfg.AllowPrivateTargets),
+	}).DialContext
+
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			// Redirects are not followed; a receiver must answer at its URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Wake makes Run look for due deliveries now rather than at its next poll
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due webhooks until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// tick attempts due deliveries until none are left
+func (d *Dispatcher) tick(ctx context.Context) {
+	// A claim outlives the attempts of its batch, which run in parallel
+	lease := d.cfg.Timeout + 30*time.Second
+
+	for ctx.Err() == nil {
+		due, err := d.repo.ClaimDue(dispatchBatch, lease)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to claim webhook deliveries")
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := range due {
+			wg.Add(1)
+			go func(delivery *models.DueDelivery) {
+				defer wg.Done()
+				d.attempt(ctx, delivery)
+			}(&due[i])
+		}
+		wg.Wait()
+
+		if len(due) < dispatchBatch {
+			return
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.DueDelivery) {
+	log := logrus.WithFields(logrus.Fields{
+		"webhook_id":  delivery.WebhookID,
+		"delivery_id": delivery.ID,
+		"attempt":     delivery.Attempts + 1,
+	})
+
+	status, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down; the claim expires and the attempt is repeated
+		return
+	}
+	if err == nil {
+		if err := d.repo.MarkDelivered(delivery.ID, status); err != nil {
+			log.WithError(err).Error("Failed to record webhook delivery")
+		}
+		return
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+	failures := delivery.Attempts + 1
+	if failures >= d.cfg.MaxAttempts {
+		log.WithError(err).Warn("Webhook delivery dead-lettered")
+		err = d.repo.MarkDead(delivery.ID, responseStatus, err.Error())
+	} else {
+		log.WithError(err).Info("Webhook delivery failed; will retry")
+		err = d.repo.MarkFailed(delivery.ID, responseStatus, err.Error(), d.retryDelay(failures))
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to record webhook delivery")
+	}
+}
+
+// send posts a delivery and returns the response status, or 0 if there was
+// no response. Any status other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.DueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManager-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts: RetryBase, doubling with each further failure up to RetryMax
+func (d *Dispatcher) retryDelay(failures int) time.Duration {
+	delay := d.cfg.RetryBase
+	for i := 1; i < failures && delay < d.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.RetryMax {
+		delay = d.cfg.RetryMax
+	}
+	return delay
+}
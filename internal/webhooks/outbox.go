@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/models"
+)
+
+// Event types of deliveries that do not come from the event broker
+const (
+	PingEvent         = "webhook.ping"         // test deliveries
+	NotificationEvent = "notification.created" // notifications sent through the webhook channel
+)
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	EventID   int64           `json:"event_id,omitempty"`
+	Type      string          `json:"type"`
+	TaskID    int             `json:"task_id,omitempty"`
+	ProjectID *int            `json:"project_id,omitempty"`
+	ActorID   int             `json:"actor_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Outbox queues published events for the webhooks subscribed to them. It is
+// an events.Sink, so each event is queued once however many API instances
+// run.
+//
+// Queueing is best-effort: events are published after the change they
+// describe has been committed, in a transaction of their own, so a crash or
+// database error in between loses the event's deliveries. Once queued, a
+// delivery is retried until it succeeds or is dead-lettered.
+type Outbox struct {
+	repo       *models.WebhookRepository
+	dispatcher *Dispatcher
+}
+
+// NewOutbox creates an outbox. dispatcher, if not nil, is woken whenever
+// deliveries are queued instead of waiting for its next poll.
+func NewOutbox(repo *models.WebhookRepository, dispatcher *Dispatcher) *Outbox {
+	return &Outbox{repo: repo, dispatcher: dispatcher}
+}
+
+// Accept queues event for its webhooks. An error means no delivery was
+// queued; the event is not retried.
+func (o *Outbox) Accept(ctx context.Context, event *events.Event) error {
+	payload, err := json.Marshal(Payload{
+		EventID:   event.ID,
+		Type:      event.Type,
+		TaskID:    event.TaskID,
+		ProjectID: event.ProjectID,
+		ActorID:   event.ActorID,
+		Data:      event.Data,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	queued, err := o.repo.Enqueue(ctx, event, payload)
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		o.wake()
+	}
+	return nil
+}
+
+// Ping queues a test delivery to a single webhook
+func (o *Outbox) Ping(webhook *models.Webhook) (*models.WebhookDelivery, error) {
+	data, err := json.Marshal(map[string]int{"webhook_id": webhook.ID})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(Payload{Type: PingEvent, Data: data, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := o.repo.EnqueueFor(webhook.ID, PingEvent, payload)
+	if err != nil {
+		return nil, err
+	}
+	o.wake()
+	return delivery, nil
+}
+
+// SendToUser queues a delivery of data to a user's personal webhooks that
+// are subscribed to eventType, and returns how many were queued
+func (o *Outbox) SendToUser(ctx context.Context, userID int, eventType string, data interface{}) (int64, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(Payload{Type: eventType, Data: encoded, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return 0, err
+	}
+
+	queued, err := o.repo.EnqueueForUser(ctx, userID, eventType, payload)
+	if err != nil {
+		return 0, err
+	}
+	if queued > 0 {
+		o.wake()
+	}
+	return queued, nil
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts
+func (o *Outbox) Redeliver(id int64) (*models.WebhookDelivery, error) {
+	delivery, err := o.repo.Redeliver(id)
+	if err != nil {
+		return nil, err
+	}
+	o.wake()
+	return delivery, nil
+}
+
+// wake tells the dispatcher there is work
+func (o *Outbox) wake() {
+	if o.dispatcher != nil {
+		o.dispatcher.Wake()
+	}
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenTarget is returned for webhook URLs that point at loopback,
+// private, link-local or unspecified addresses, unless
+// config.WebhookConfig.AllowPrivateTargets is set. Webhooks must not become
+// a way to reach the services next to the API.
+var ErrForbiddenTarget = errors.New("webhook URL must not point at a loopback, private or link-local address")
+
+// ErrInvalidTarget is returned for webhook URLs that are not absolute http
+// or https URLs
+var ErrInvalidTarget = errors.New("url must be an http or https URL")
+
+// allowedIP reports whether ip may receive webhooks
+func allowedIP(ip net.IP, allowPrivate bool) bool {
+	if allowPrivate {
+		return true
+	}
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// CheckTarget checks that rawURL is an http or https URL whose host only
+// resolves to public addresses, or to any address with allowPrivate.
+// Delivery checks the address again when it connects, since DNS may answer
+// differently by then.
+func CheckTarget(ctx context.Context, rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidTarget
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !allowedIP(ip, allowPrivate) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.New("url host could not be resolved")
+	}
+	for _, addr := range addrs {
+		if !allowedIP(addr.IP, allowPrivate) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// checkDial returns a net.Dialer Control hook that refuses connections to
+// addresses that may not receive webhooks
+func checkDial(allowPrivate bool) func(network, address string, _ syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !allowedIP(ip, allowPrivate) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+// Package worker runs the background work of the application: the periodic
+// schedulers, reminders and notifications, webhook delivery and the job
+// queue. It runs either embedded in
+// the API process or on its own as cmd/worker.
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/Task_Management/internal/config"
+	"github.com/yourusername/Task_Management/internal/events"
+	"github.com/yourusername/Task_Management/internal/jobs"
+	"github.com/yourusername/Task_Management/internal/mail"
+	"github.com/yourusername/Task_Management/internal/models"
+	"github.com/yourusername/Task_Management/internal/notify"
+	"github.com/yourusername/Task_Management/internal/scheduler"
+	"github.com/yourusername/Task_Management/internal/storage"
+	"github.com/yourusername/Task_Management/internal/webhooks"
+)
+
+// Runner owns the background workers
+type Runner struct {
+	recurring     *scheduler.RecurringScheduler
+	purger        *scheduler.TokenPurger
+	sweeper       *scheduler.AttachmentSweeper
+	notifications *scheduler.NotificationSweeper
+	dispatcher    *webhooks.Dispatcher
+	jobs          *jobs.Worker
+}
+
+// NewRunner creates the background workers. Tasks they create are announced
+// on broker.
+func NewRunner(cfg *config.Config, db *sqlx.DB, store storage.Storage, mailer mail.Mailer, broker *events.Broker) *Runner {
+	webhookRepo := models.NewWebhookRepository(db)
+	dispatcher := webhooks.NewDispatcher(webhookRepo, cfg.Webhooks)
+
+	jobRepo := models.NewJobRepository(db)
+	notificationRepo := models.NewNotificationRepository(db)
+	notifier := notify.NewNotifier(jobs.NewQueue(jobRepo), notificationRepo, models.NewUserRepository(db))
+	notifier.AddChannel(models.ChannelEmail, notify.NewEmailChannel(mailer, cfg.AppBaseURL))
+	notifier.AddChannel(models.ChannelWebhook, notify.NewWebhookChannel(webhooks.NewOutbox(webhookRepo, dispatcher)))
+
+	// Handlers of every job kind are registered here
+	registry := jobs.NewRegistry()
+	notifier.Register(registry)
+
+	return &Runner{
+		recurring: scheduler.NewRecurringScheduler(
+			models.NewSeriesRepository(db),
+			models.NewTaskRepository(db),
+			models.NewProjectRepository(db),
+			models.NewAssignmentRepository(db),
+			broker,
+			cfg.SchedulerInterval,
+		),
+		purger: scheduler.NewTokenPurger(
+			models.NewTokenRepository(db),
+			models.NewUserTokenRepository(db),
+			models.NewMFARepository(db),
+			models.NewLoginThrottleRepository(db),
+			cfg.LoginThrottle.Window,
+			time.Hour,
+		),
+		sweeper: scheduler.NewAttachmentSweeper(models.NewAttachmentRepository(db), store, cfg.SchedulerInterval),
+		notifications: scheduler.NewNotificationSweeper(
+			models.NewReminderRepository(db),
+			notificationRepo,
+			notifier,
+			cfg.SchedulerInterval,
+		),
+		dispatcher: dispatcher,
+		jobs:       jobs.NewWorker(jobRepo, registry, cfg.Jobs),
+	}
+}
+
+// Webhooks returns the webhook dispatcher, which an outbox in the same
+// process wakes when it queues deliveries
+func (r *Runner) Webhooks() *webhooks.Dispatcher {
+	return r.dispatcher
+}
+
+// Run runs every worker until ctx is cancelled and they have all stopped
+func (r *Runner) Run(ctx context.Context) {
+	workers := []func(context.Context){
+		r.recurring.Run,
+		r.purger.Run,
+		r.sweeper.Run,
+		r.notifications.Run,
+		r.dispatcher.Run,
+		r.jobs.Run,
+	}
+
+	var wg sync.WaitGroup
+	for _, run := range workers {
+		wg.Add(1)
+		go func(run func(context.Context)) {
+			defer wg.Done()
+			run(ctx)
+		}(run)
+	}
+	wg.Wait()
+}